@@ -0,0 +1,130 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// AsyncFunction creates a JavaScript function that runs the Go function fn
+// in a new goroutine and returns a Promise.
+//
+// Arguments are converted as described in Bind (before starting the goroutine),
+// and the Promise is resolved with the marshaled result, or rejected with
+// the returned error, on the main thread (see App.Run and Renderer.Update).
+//
+// fn should not call into the View or JavaScript, since it doesn't run on
+// the main thread.
+func (ctx *JSContext) AsyncFunction(name string, fn interface{}) (*JSValue, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("expected a function, got %T", fn)
+	}
+
+	ft := fv.Type()
+	if err := checkResults(ft); err != nil {
+		return nil, err
+	}
+
+	cb := func(function, this *JSObject, args ...*JSValue) (*JSValue, error) {
+		// the calling context may not outlive this call, use the global one
+		gctx := C.JSContextGetGlobalContext(function.ctx)
+		jctx := &JSContext{ctx: C.JSContextRef(gctx)}
+
+		promise, resolve, reject, err := jctx.newPromise()
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the context and the resolving functions alive until the Promise
+		// is settled (decoding the arguments can already trigger a collection)
+		C.JSGlobalContextRetain(gctx)
+		C.JSValueProtect(jctx.ctx, resolve.val)
+		C.JSValueProtect(jctx.ctx, reject.val)
+
+		release := func() {
+			C.JSValueUnprotect(jctx.ctx, resolve.val)
+			C.JSValueUnprotect(jctx.ctx, reject.val)
+			C.JSGlobalContextRelease(gctx)
+		}
+
+		in, err := callArgs(ft, args)
+		if err != nil {
+			jctx.settle(reject, jctx.errorValue(err))
+			release()
+			return promise, nil
+		}
+
+		go func() {
+			out, err := safeCall(fv, in)
+
+			runOnMain(func() {
+				defer release()
+
+				var ret *JSValue
+				if err == nil {
+					ret, err = callResults(jctx, out)
+				}
+
+				if err != nil {
+					jctx.settle(reject, jctx.errorValue(err))
+				} else {
+					jctx.settle(resolve, ret.val)
+				}
+			})
+		}()
+
+		return promise, nil
+	}
+
+	return ctx.FunctionCallbackErr(name, cb), nil
+}
+
+// newPromise creates a pending Promise, returning its resolving functions.
+func (ctx *JSContext) newPromise() (promise, resolve, reject *JSValue, err error) {
+	executor := ctx.FunctionCallback("", func(function, this *JSObject, args ...*JSValue) *JSValue {
+		if len(args) == 2 {
+			resolve, reject = args[0], args[1]
+		}
+
+		return nil
+	})
+
+	ctor := ctx.GlobalObject().Property("Promise")
+	if !ctor.IsObject() {
+		return nil, nil, nil, errors.New("Promise is not available")
+	}
+
+	var exc C.JSValueRef
+
+	p := C.JSObjectCallAsConstructor(ctx.ctx, ctor.Object().obj, 1, &executor.val, &exc)
+	if exc != nil {
+		return nil, nil, nil, newJSError(ctx.ctx, exc)
+	}
+
+	if resolve == nil || reject == nil {
+		return nil, nil, nil, errors.New("Promise executor not called")
+	}
+
+	return &JSValue{ctx: ctx.ctx, val: C.JSValueRef(p)}, resolve, reject, nil
+}
+
+// settle calls a Promise resolving function with the specified value.
+func (ctx *JSContext) settle(fn *JSValue, value C.JSValueRef) {
+	C.JSObjectCallAsFunction(ctx.ctx, C.JSObjectRef(fn.val), nil, 1, &value, nil)
+}
+
+// safeCall calls fv, returning panics as errors.
+func safeCall(fv reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fv.Call(in), nil
+}
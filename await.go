@@ -0,0 +1,98 @@
+package ultralight
+
+import "context"
+
+// IsPromise tests whether a JavaScript value is a Promise
+// (or any "thenable" object).
+func (v *JSValue) IsPromise() bool {
+	if !v.IsObject() {
+		return false
+	}
+
+	return v.Object().Property("then").IsFunction()
+}
+
+// Then calls cb when the Promise settles, with the fulfillment value or
+// with a *JSError for the rejection reason. If the value is not a Promise
+// cb is called immediately with the value itself.
+//
+// cb is called on the main thread.
+func (v *JSValue) Then(cb func(result *JSValue, err error)) error {
+	if !v.IsPromise() {
+		cb(v, nil)
+		return nil
+	}
+
+	ctx := &JSContext{ctx: v.ctx}
+	settled := false
+
+	onFulfilled := ctx.FunctionCallback("", func(function, this *JSObject, args ...*JSValue) *JSValue {
+		if !settled {
+			settled = true
+			cb(argOrUndefined(ctx, args), nil)
+		}
+		return nil
+	})
+
+	// the callbacks are only referenced by the Promise once "then" returns
+	defer protect(ctx.ctx, onFulfilled.Object().obj)()
+
+	onRejected := ctx.FunctionCallback("", func(function, this *JSObject, args ...*JSValue) *JSValue {
+		if !settled {
+			settled = true
+			reason := argOrUndefined(ctx, args)
+			cb(nil, newJSError(reason.ctx, reason.val))
+		}
+		return nil
+	})
+	defer protect(ctx.ctx, onRejected.Object().obj)()
+
+	o := v.Object()
+	_, err := o.Property("then").Object().CallErr(o, onFulfilled, onRejected)
+	return err
+}
+
+func argOrUndefined(ctx *JSContext, args []*JSValue) *JSValue {
+	if len(args) > 0 {
+		return args[0]
+	}
+
+	undef := ctx.Undefined()
+	return &undef
+}
+
+// Await waits for a Promise to settle, returning the fulfillment value
+// or a *JSError with the rejection reason. If the value is not a Promise
+// it's returned as is.
+//
+// Await runs the Renderer event loop (see Renderer.Update) until the Promise
+// settles or ctx is done, so it can only be used in offscreen mode, from
+// the thread calling Renderer.Update. With App, use Then.
+func (v *JSValue) Await(ctx context.Context) (*JSValue, error) {
+	if !v.IsPromise() {
+		return v, nil
+	}
+
+	if currentRenderer == nil {
+		return nil, errNoRenderer
+	}
+
+	var result *JSValue
+	var rerr error
+
+	done := false
+
+	err := v.Then(func(res *JSValue, err error) {
+		result, rerr = res, err
+		done = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pumpUntil(ctx, func() bool { return done }); err != nil {
+		return nil, err
+	}
+
+	return result, rerr
+}
@@ -0,0 +1,167 @@
+package ultralight
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Bind exposes all the exported methods of obj to JavaScript, as the
+// methods of a global object called name (or as global functions if
+// name is empty).
+//
+// Arguments are converted to the method parameter types using Decode
+// (missing arguments are passed as zero values), and return values are
+// converted using Marshal. If the last return value is an error and is
+// not nil, it is thrown as a JavaScript exception.
+func (ctx *JSContext) Bind(name string, obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if !rv.IsValid() {
+		return errors.New("Bind: nil object")
+	}
+
+	global := ctx.GlobalObject()
+	target := global
+	if name != "" {
+		target = ctx.Object()
+		defer protect(ctx.ctx, target.obj)()
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumMethod(); i++ {
+		m := rt.Method(i)
+
+		fn, err := ctx.Function(m.Name, rv.Method(i).Interface())
+		if err != nil {
+			return fmt.Errorf("Bind: method %v: %w", m.Name, err)
+		}
+
+		target.SetProperty(m.Name, fn)
+	}
+
+	if name != "" {
+		global.SetProperty(name, target.Value())
+	}
+
+	return nil
+}
+
+// Function creates a JavaScript function that calls the Go function fn,
+// converting arguments and return values as described in Bind.
+func (ctx *JSContext) Function(name string, fn interface{}) (*JSValue, error) {
+	switch t := fn.(type) {
+	case FunctionCallback:
+		return ctx.FunctionCallback(name, t), nil
+
+	case func(function, this *JSObject, args ...*JSValue) *JSValue:
+		return ctx.FunctionCallback(name, t), nil
+
+	case FunctionCallbackErr:
+		return ctx.FunctionCallbackErr(name, t), nil
+
+	case func(function, this *JSObject, args ...*JSValue) (*JSValue, error):
+		return ctx.FunctionCallbackErr(name, t), nil
+	}
+
+	cb, err := reflectCallback(reflect.ValueOf(fn))
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.FunctionCallbackErr(name, cb), nil
+}
+
+func checkResults(ft reflect.Type) error {
+	switch ft.NumOut() {
+	case 0, 1:
+		return nil
+
+	case 2:
+		if ft.Out(1) == errorType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported results for %v (expected at most a value and an error)", ft)
+}
+
+func reflectCallback(fv reflect.Value) (FunctionCallbackErr, error) {
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("expected a function, got %v", fv.Type())
+	}
+
+	ft := fv.Type()
+	if err := checkResults(ft); err != nil {
+		return nil, err
+	}
+
+	return func(function, this *JSObject, args ...*JSValue) (*JSValue, error) {
+		in, err := callArgs(ft, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return callResults(&JSContext{ctx: function.ctx}, fv.Call(in))
+	}, nil
+}
+
+// callArgs decodes the JavaScript arguments into the parameters of a
+// function of type ft.
+func callArgs(ft reflect.Type, args []*JSValue) ([]reflect.Value, error) {
+	nin := ft.NumIn()
+	in := make([]reflect.Value, 0, nin)
+
+	for i := 0; i < nin; i++ {
+		pt := ft.In(i)
+
+		if ft.IsVariadic() && i == nin-1 {
+			for j := i; j < len(args); j++ {
+				ev := reflect.New(pt.Elem())
+				if err := args[j].Decode(ev.Interface()); err != nil {
+					return nil, fmt.Errorf("argument %v: %w", j, err)
+				}
+
+				in = append(in, ev.Elem())
+			}
+
+			break
+		}
+
+		pv := reflect.New(pt)
+		if i < len(args) {
+			if err := args[i].Decode(pv.Interface()); err != nil {
+				return nil, fmt.Errorf("argument %v: %w", i, err)
+			}
+		}
+
+		in = append(in, pv.Elem())
+	}
+
+	return in, nil
+}
+
+// callResults converts the results of a function call (see checkResults)
+// to a JavaScript value or an error.
+func callResults(ctx *JSContext, out []reflect.Value) (*JSValue, error) {
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+
+		out = out[:n-1]
+	}
+
+	if len(out) == 0 {
+		ret := ctx.Undefined()
+		return &ret, nil
+	}
+
+	ret, err := ctx.Marshal(out[0].Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
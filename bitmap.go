@@ -0,0 +1,144 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+#include <stdlib.h>
+*/
+import "C"
+import "unsafe"
+import "image"
+
+type BitmapFormat int
+
+const (
+	BitmapFormatA8    = BitmapFormat(C.kBitmapFormat_A8_UNORM)
+	BitmapFormatBGRA8 = BitmapFormat(C.kBitmapFormat_BGRA8_UNORM_SRGB)
+)
+
+// Bitmap is a View's pixel buffer.
+//
+// The bitmap is owned by the View, so it is only valid until the View
+// is resized or destroyed.
+type Bitmap struct {
+	bmp  C.ULBitmap
+	bgra bool
+}
+
+// Get the width in pixels.
+func (b *Bitmap) Width() uint {
+	return uint(C.ulBitmapGetWidth(b.bmp))
+}
+
+// Get the height in pixels.
+func (b *Bitmap) Height() uint {
+	return uint(C.ulBitmapGetHeight(b.bmp))
+}
+
+// Get the pixel format.
+func (b *Bitmap) Format() BitmapFormat {
+	return BitmapFormat(C.ulBitmapGetFormat(b.bmp))
+}
+
+// Get the bytes per pixel.
+func (b *Bitmap) Bpp() uint {
+	return uint(C.ulBitmapGetBpp(b.bmp))
+}
+
+// Get the number of bytes per row.
+func (b *Bitmap) RowBytes() uint {
+	return uint(C.ulBitmapGetRowBytes(b.bmp))
+}
+
+// Get the size in bytes of the underlying pixel buffer.
+func (b *Bitmap) Size() int {
+	return int(C.ulBitmapGetSize(b.bmp))
+}
+
+// Check whether or not this bitmap is empty.
+func (b *Bitmap) IsEmpty() bool {
+	return bool(C.ulBitmapIsEmpty(b.bmp))
+}
+
+// Check whether Pixels returns the pixels in BGRA byte order, instead of RGBA
+// (see Config.UseBGRAForOffscreenRendering).
+func (b *Bitmap) IsBGRA() bool {
+	return b.bgra
+}
+
+// Lock the pixels for reading/writing, returns pointer to pixel buffer.
+// The locked pixels are in the bitmap format (i.e. BGRA, regardless of IsBGRA).
+func (b *Bitmap) LockPixels() unsafe.Pointer {
+	return C.ulBitmapLockPixels(b.bmp)
+}
+
+// Unlock the pixels after locking.
+func (b *Bitmap) UnlockPixels() {
+	C.ulBitmapUnlockPixels(b.bmp)
+}
+
+// Pixels returns a copy of the pixel buffer.
+//
+// Ultralight paints in BGRA: the red and blue channels of BGRA8 bitmaps are
+// swapped in the copy, unless IsBGRA.
+func (b *Bitmap) Pixels() []byte {
+	pix := b.copyPixels()
+	if !b.bgra && b.Format() == BitmapFormatBGRA8 {
+		stride, width := int(b.RowBytes()), int(b.Width())*4
+		for y := 0; y+width <= len(pix); y += stride {
+			swapRedBlue(pix[y : y+width])
+		}
+	}
+
+	return pix
+}
+
+// copyPixels returns a copy of the pixel buffer, in the bitmap format.
+func (b *Bitmap) copyPixels() []byte {
+	p := b.LockPixels()
+	defer b.UnlockPixels()
+
+	return C.GoBytes(p, C.int(b.Size()))
+}
+
+// swapRedBlue converts BGRA pixels to RGBA (and back).
+func swapRedBlue(pix []byte) {
+	for i := 0; i+3 < len(pix); i += 4 {
+		pix[i], pix[i+2] = pix[i+2], pix[i]
+	}
+}
+
+// Image returns a copy of the bitmap as an image.
+//
+// BGRA8 bitmaps are returned as *image.RGBA, A8 bitmaps as *image.Alpha.
+func (b *Bitmap) Image() image.Image {
+	width, height := int(b.Width()), int(b.Height())
+	stride := int(b.RowBytes())
+	pix := b.copyPixels()
+	rect := image.Rect(0, 0, width, height)
+
+	if b.Format() == BitmapFormatA8 {
+		img := image.NewAlpha(rect)
+		for y := 0; y < height; y++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+width], pix[y*stride:])
+		}
+
+		return img
+	}
+
+	img := image.NewRGBA(rect)
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+width*4]
+		copy(row, pix[y*stride:])
+		swapRedBlue(row)
+	}
+
+	return img
+}
+
+// Write bitmap to a PNG on disk.
+func (b *Bitmap) WritePNG(filename string) bool {
+	path := C.CString(filename)
+	defer C.free(unsafe.Pointer(path))
+
+	return bool(C.ulBitmapWritePNG(b.bmp, path))
+}
@@ -0,0 +1,238 @@
+package ultralight
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// CaptureTileHeight is the maximum View height (in device coordinates) used
+// by CaptureFullPage. Taller pages are captured in tiles, scrolling the page.
+var CaptureTileHeight uint = 4096
+
+// ErrNoBitmap is returned by the capture functions when the View doesn't paint
+// into a bitmap (i.e. with a SurfaceFactory, see View.Image).
+var ErrNoBitmap = errors.New("the view has no bitmap")
+
+// pageMetrics are the page dimensions, in CSS pixels.
+type pageMetrics struct {
+	ScrollWidth  float64
+	ScrollHeight float64
+	InnerWidth   float64
+	ScrollX      float64
+	ScrollY      float64
+}
+
+func (view *View) pageMetrics() (*pageMetrics, error) {
+	v, err := view.EvaluateScriptErr(`({
+		ScrollWidth: document.documentElement.scrollWidth,
+		ScrollHeight: document.documentElement.scrollHeight,
+		InnerWidth: window.innerWidth,
+		ScrollX: window.scrollX,
+		ScrollY: window.scrollY
+	})`)
+	if err != nil {
+		return nil, err
+	}
+
+	var m pageMetrics
+	if err := v.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (view *View) scrollTo(x, y float64) error {
+	_, err := view.EvaluateScriptErr(fmt.Sprintf("window.scrollTo(%v, %v)", x, y))
+	return err
+}
+
+// CaptureFullPage renders the whole page, including the content outside the
+// View viewport, and returns it as a single image.
+//
+// The View is temporarily resized to the page width, and the page is captured
+// in tiles of CaptureTileHeight, scrolling between tiles (so elements with
+// fixed position will appear in every tile). The original size and scroll
+// position are restored at the end.
+//
+// Like Await, this runs the Renderer event loop, so it can only be used in
+// offscreen mode.
+func (view *View) CaptureFullPage() (image.Image, error) {
+	r := currentRenderer
+	if r == nil {
+		return nil, errNoRenderer
+	}
+
+	m, err := view.pageMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	origWidth, origHeight := view.Width(), view.Height()
+	origX, origY := m.ScrollX, m.ScrollY
+
+	// device coordinates per CSS pixel
+	ratio := 1.0
+	if m.InnerWidth > 0 {
+		ratio = float64(origWidth) / m.InnerWidth
+	}
+
+	defer func() {
+		view.Resize(origWidth, origHeight)
+		view.scrollTo(origX, origY)
+		r.Update()
+		r.Render()
+	}()
+
+	width := uint(math.Ceil(m.ScrollWidth * ratio))
+	if width < origWidth {
+		width = origWidth
+	}
+
+	if width != origWidth {
+		// the height may change with the width
+		view.Resize(width, origHeight)
+		r.Update()
+
+		if m, err = view.pageMetrics(); err != nil {
+			return nil, err
+		}
+	}
+
+	height := uint(math.Ceil(m.ScrollHeight * ratio))
+	if height == 0 {
+		return nil, errors.New("empty page")
+	}
+
+	tileHeight := height
+	if tileHeight > CaptureTileHeight {
+		tileHeight = CaptureTileHeight
+	}
+
+	view.Resize(width, tileHeight)
+
+	var dst *image.RGBA
+	var scale float64 // bitmap pixels per device coordinate
+
+	for y := uint(0); y < height; y += tileHeight {
+		if err := view.scrollTo(0, float64(y)/ratio); err != nil {
+			return nil, err
+		}
+
+		r.Update()
+		r.Render()
+
+		tile := view.Image()
+		if tile == nil {
+			return nil, ErrNoBitmap
+		}
+
+		if dst == nil {
+			scale = float64(tile.Bounds().Dx()) / float64(width)
+			dst = image.NewRGBA(image.Rect(0, 0,
+				int(math.Ceil(float64(width)*scale)),
+				int(math.Ceil(float64(height)*scale))))
+		}
+
+		// the last tile may not scroll all the way, use the actual position
+		sm, err := view.pageMetrics()
+		if err != nil {
+			return nil, err
+		}
+
+		top := int(math.Round(sm.ScrollY * ratio * scale))
+		draw.Draw(dst, tile.Bounds().Add(image.Pt(0, top)), tile, image.Point{}, draw.Src)
+	}
+
+	return dst, nil
+}
+
+// elementRectScript returns the bounding rectangle of the element matching
+// a selector (in CSS pixels, relative to the viewport) and the viewport width.
+const elementRectScript = `(function(selector) {
+	var el = document.querySelector(selector);
+	if (!el) return null;
+	var r = el.getBoundingClientRect();
+	return {Left: r.left, Top: r.top, Right: r.right, Bottom: r.bottom, InnerWidth: window.innerWidth};
+})`
+
+// ElementRect returns the bounding box of the first element matching the
+// CSS selector, relative to the View viewport and in device coordinates
+// (the same used by Click and the other input methods).
+func (view *View) ElementRect(selector string) (image.Rectangle, error) {
+	fn, err := view.EvaluateScriptErr(elementRectScript)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	v, err := fn.Object().CallErr(nil, selector)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	var r *struct {
+		Left, Top, Right, Bottom float64
+		InnerWidth               float64
+	}
+
+	if err := v.Decode(&r); err != nil {
+		return image.Rectangle{}, err
+	}
+
+	if r == nil {
+		return image.Rectangle{}, fmt.Errorf("no element matching %q", selector)
+	}
+
+	// device coordinates per CSS pixel
+	ratio := 1.0
+	if r.InnerWidth > 0 {
+		ratio = float64(view.Width()) / r.InnerWidth
+	}
+
+	return image.Rect(
+		int(math.Floor(r.Left*ratio)),
+		int(math.Floor(r.Top*ratio)),
+		int(math.Ceil(r.Right*ratio)),
+		int(math.Ceil(r.Bottom*ratio))), nil
+}
+
+// CaptureElement returns the image of the first element matching the CSS
+// selector, cropped from the current View bitmap (it doesn't render the View,
+// so call Renderer.Render first if needed).
+//
+// Only the visible part of the element is returned. It's an error if the
+// element is completely outside of the viewport.
+func (view *View) CaptureElement(selector string) (image.Image, error) {
+	rect, err := view.ElementRect(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	img := view.Image()
+	if img == nil {
+		return nil, ErrNoBitmap
+	}
+
+	// bitmap pixels per device coordinate
+	scale := 1.0
+	if w := view.Width(); w > 0 {
+		scale = float64(img.Bounds().Dx()) / float64(w)
+	}
+
+	crop := image.Rect(
+		int(math.Floor(float64(rect.Min.X)*scale)),
+		int(math.Floor(float64(rect.Min.Y)*scale)),
+		int(math.Ceil(float64(rect.Max.X)*scale)),
+		int(math.Ceil(float64(rect.Max.Y)*scale))).Intersect(img.Bounds())
+
+	if crop.Empty() {
+		return nil, fmt.Errorf("element %q is not visible", selector)
+	}
+
+	return img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}).SubImage(crop), nil
+}
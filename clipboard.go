@@ -0,0 +1,112 @@
+package ultralight
+
+/*
+#include <stdlib.h>
+#include <AppCore/CAPI.h>
+
+extern void clipboardClearCallback();
+extern void clipboardReadPlainTextCallback(ULString);
+extern void clipboardWritePlainTextCallback(ULString);
+
+static inline void set_platform_clipboard() {
+        ULClipboard clipboard = { 0 };
+        clipboard.clear = clipboardClearCallback;
+        clipboard.read_plain_text = clipboardReadPlainTextCallback;
+        clipboard.write_plain_text = clipboardWritePlainTextCallback;
+        ulPlatformSetClipboard(clipboard);
+}
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// Clipboard is the clipboard used for copy and paste in views.
+//
+// Methods may be called from any thread.
+type Clipboard interface {
+	Clear()
+	ReadPlainText() string
+	WritePlainText(text string)
+}
+
+var platformClipboard struct {
+	sync.RWMutex
+	clipboard Clipboard
+}
+
+// SetClipboard sets the clipboard used by Ultralight. If clipboard is nil,
+// copied text is discarded and pasting returns an empty string.
+// This should be called before creating the App or Renderer.
+func SetClipboard(clipboard Clipboard) {
+	platformClipboard.Lock()
+	platformClipboard.clipboard = clipboard
+	platformClipboard.Unlock()
+
+	// the callbacks are kept once set, since Ultralight may still call them
+	C.set_platform_clipboard()
+}
+
+func clipboard() Clipboard {
+	platformClipboard.RLock()
+	defer platformClipboard.RUnlock()
+
+	return platformClipboard.clipboard
+}
+
+// MemoryClipboard is an in-memory Clipboard, useful for headless rendering and tests.
+// The zero value is an empty clipboard.
+type MemoryClipboard struct {
+	mu   sync.Mutex
+	text string
+}
+
+// Clear implements Clipboard.
+func (c *MemoryClipboard) Clear() {
+	c.mu.Lock()
+	c.text = ""
+	c.mu.Unlock()
+}
+
+// ReadPlainText implements Clipboard.
+func (c *MemoryClipboard) ReadPlainText() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.text
+}
+
+// WritePlainText implements Clipboard.
+func (c *MemoryClipboard) WritePlainText(text string) {
+	c.mu.Lock()
+	c.text = text
+	c.mu.Unlock()
+}
+
+//export clipboardClearCallback
+func clipboardClearCallback() {
+	if c := clipboard(); c != nil {
+		c.Clear()
+	}
+}
+
+//export clipboardReadPlainTextCallback
+func clipboardReadPlainTextCallback(result C.ULString) {
+	var text string
+
+	if c := clipboard(); c != nil {
+		text = c.ReadPlainText()
+	}
+
+	s := C.CString(text)
+	C.ulStringAssignCString(result, s)
+	C.free(unsafe.Pointer(s))
+}
+
+//export clipboardWritePlainTextCallback
+func clipboardWritePlainTextCallback(text C.ULString) {
+	if c := clipboard(); c != nil {
+		c.WritePlainText(decodeULString(text))
+	}
+}
@@ -0,0 +1,316 @@
+// Command ultralight-render renders web pages (URLs, HTML files or HTML from
+// stdin) to PNG or JPEG images, using the Ultralight offscreen renderer.
+//
+// Usage:
+//
+//	ultralight-render [flags] input...
+//
+// Each input is a URL (anything with a "://"), the name of an HTML file or "-"
+// for stdin. With a single input the image is written to the file specified
+// with -o, with multiple inputs (or -list) the images are written to -outdir,
+// named after the inputs, all in the same renderer lifetime.
+//
+// HTML files are loaded as file:/// URLs, so that relative links, images and
+// stylesheets are resolved from the file directory. Only the files in the
+// directories of the input files (and in the SDK resources) can be loaded.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raff/ultralight-go"
+)
+
+var (
+	width       = flag.Uint("width", 1024, "viewport width")
+	height      = flag.Uint("height", 768, "viewport height")
+	scale       = flag.Float64("scale", 1.0, "device scale")
+	transparent = flag.Bool("transparent", false, "transparent background")
+	css         = flag.String("css", "", "user stylesheet file")
+	wait        = flag.String("wait", "load", "wait condition: load, domready or networkidle")
+	delay       = flag.Duration("delay", 0, "additional time to wait after the page is loaded")
+	timeout     = flag.Duration("timeout", 30*time.Second, "timeout for loading each page")
+	output      = flag.String("o", "", "output file, for a single input (default: derived from the input name)")
+	outdir      = flag.String("outdir", ".", "output directory, for multiple inputs")
+	format      = flag.String("format", "png", "output format: png or jpeg")
+	quality     = flag.Int("quality", 90, "JPEG quality")
+	list        = flag.String("list", "", "file containing a list of inputs, one per line")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %v [flags] input...\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	inputs := flag.Args()
+	if *list != "" {
+		b, err := os.ReadFile(*list)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, l := range strings.Split(string(b), "\n") {
+			if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+				inputs = append(inputs, l)
+			}
+		}
+	}
+
+	if len(inputs) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *output != "" && len(inputs) > 1 {
+		log.Fatal("-o can only be used with a single input")
+	}
+
+	var cond ultralight.WaitCondition
+
+	switch *wait {
+	case "load":
+		cond = ultralight.WaitLoad
+	case "domready":
+		cond = ultralight.WaitDOMReady
+	case "networkidle":
+		cond = ultralight.WaitNetworkIdle
+	default:
+		log.Fatalf("invalid wait condition %q", *wait)
+	}
+
+	ext := "." + *format
+	switch *format {
+	case "png":
+	case "jpeg", "jpg":
+		ext = ".jpg"
+	default:
+		log.Fatalf("invalid format %q", *format)
+	}
+
+	c := ultralight.NewConfig(ultralight.DeviceScaleHint(*scale))
+	defer c.Destroy()
+
+	// HTML files are loaded by absolute path, so the SDK resources
+	// (in the current directory) are too.
+	resources, err := filepath.Abs("resources")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dirs := dirsFS{fsPath(resources)}
+	for _, input := range inputs {
+		if input != "-" && !strings.Contains(input, "://") {
+			if abs, err := filepath.Abs(input); err == nil {
+				dirs = append(dirs, fsPath(filepath.Dir(abs)))
+			}
+		}
+	}
+
+	ultralight.SetFileSystem(dirs)
+	c.ResourcePath(fsPath(resources) + "/")
+
+	if *css != "" {
+		b, err := os.ReadFile(*css)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.UserStylesheet(string(b))
+	}
+
+	r := ultralight.NewRenderer(c)
+	defer r.Destroy()
+
+	failed := 0
+	names := map[string]bool{}
+
+	for i, input := range inputs {
+		out := *output
+		if out == "" {
+			name := outputName(input, i)
+			if names[name] {
+				name = fmt.Sprintf("%v-%v", name, i+1)
+			}
+
+			names[name] = true
+			out = filepath.Join(*outdir, name+ext)
+		}
+
+		if err := render(r, input, cond, out); err != nil {
+			log.Printf("%v: %v", input, err)
+			failed++
+			continue
+		}
+
+		log.Printf("%v: %v", input, out)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// render renders one input to the output file.
+func render(r *ultralight.Renderer, input string, cond ultralight.WaitCondition, out string) error {
+	v := r.NewView(*width, *height, *transparent)
+	defer v.Destroy()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var err error
+
+	switch {
+	case input == "-":
+		var b []byte
+		if b, err = io.ReadAll(os.Stdin); err == nil {
+			err = v.LoadHTMLAndWait(ctx, string(b), cond)
+		}
+
+	case strings.Contains(input, "://"):
+		err = v.LoadURLAndWait(ctx, input, cond)
+
+	default:
+		var u string
+		if u, err = fileURL(input); err == nil {
+			err = v.LoadURLAndWait(ctx, u, cond)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if *delay > 0 {
+		end := time.Now().Add(*delay)
+		for time.Now().Before(end) {
+			r.Update()
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	r.Render()
+
+	img := v.Image()
+	if img == nil {
+		return ultralight.ErrNoBitmap
+	}
+
+	return writeImage(img, out)
+}
+
+// fileURL returns the file:/// URL of an existing file.
+func fileURL(name string) (string, error) {
+	if _, err := os.Stat(name); err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	// i.e. file:///home/user/page.html or file:///C:/Users/page.html
+	u := url.URL{Scheme: "file", Path: "/" + fsPath(abs)}
+	return u.String(), nil
+}
+
+// fsPath converts an absolute path to a slash separated path without the
+// leading "/" (a valid fs.FS path, like the ones Ultralight opens).
+func fsPath(abs string) string {
+	return strings.TrimPrefix(filepath.ToSlash(abs), "/")
+}
+
+// dirsFS is a file system rooted at "/" (or at the drive letters, on Windows)
+// that only serves the files in the listed directories (see fsPath)
+// and in their subdirectories.
+type dirsFS []string
+
+func (d dirsFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) || !d.contains(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if p := filepath.FromSlash(name); filepath.VolumeName(p) != "" {
+		return os.Open(p)
+	}
+
+	return os.Open("/" + name)
+}
+
+func (d dirsFS) contains(name string) bool {
+	for _, dir := range d {
+		if dir == "" || name == dir || strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeImage(img image.Image, out string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	if *format == "png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: *quality})
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// outputName returns a file name (without extension) for the input.
+func outputName(input string, i int) string {
+	name := ""
+
+	switch {
+	case input == "-":
+		name = "stdin"
+
+	case strings.Contains(input, "://"):
+		if u, err := url.Parse(input); err == nil {
+			name = u.Host + u.Path
+		}
+
+	default:
+		name = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	}
+
+	name = strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, name), "_.")
+
+	if name == "" {
+		name = fmt.Sprintf("page%v", i+1)
+	}
+
+	return name
+}
@@ -0,0 +1,405 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+var (
+	jsValuePtrType  = reflect.TypeOf(&JSValue{})
+	jsObjectPtrType = reflect.TypeOf(&JSObject{})
+)
+
+// Decode stores the JavaScript value in the Go value pointed to by dst,
+// following the same rules as encoding/json.Unmarshal:
+//
+//   - objects decode into structs (matching `json` tags or field names,
+//     case-insensitively) and maps with string keys
+//   - arrays decode into slices and arrays
+//   - Dates decode into time.Time, Uint8Arrays into []byte
+//   - null and undefined set pointers, maps, slices and interfaces to nil
+//     and leave other values unchanged
+//   - into an interface{} value, Decode stores bool, float64, string,
+//     time.Time, []interface{} or map[string]interface{}
+//
+// A *JSValue or JSValue destination receives the value as is, and a
+// *JSObject destination receives the object.
+func (v *JSValue) Decode(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("Decode: destination must be a non-nil pointer")
+	}
+
+	return v.decode(rv.Elem())
+}
+
+func (v *JSValue) decodeError(rv reflect.Value) error {
+	return fmt.Errorf("cannot decode JS %v into Go value of type %v", v.typeName(), rv.Type())
+}
+
+func (v *JSValue) typeName() string {
+	switch v.Type() {
+	case JSTypeUndefined:
+		return "undefined"
+	case JSTypeNull:
+		return "null"
+	case JSTypeBoolean:
+		return "boolean"
+	case JSTypeNumber:
+		return "number"
+	case JSTypeString:
+		return "string"
+	}
+
+	switch {
+	case v.IsArray():
+		return "array"
+	case v.IsDate():
+		return "date"
+	case v.IsFunction():
+		return "function"
+	}
+
+	return "object"
+}
+
+func (v *JSValue) decode(rv reflect.Value) error {
+	switch rv.Type() {
+	case jsValueTyp:
+		rv.Set(reflect.ValueOf(*v))
+		return nil
+
+	case jsValuePtrType:
+		rv.Set(reflect.ValueOf(v))
+		return nil
+
+	case jsObjectPtrType:
+		if v.IsNull() || v.IsUndefined() {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if !v.IsObject() {
+			return v.decodeError(rv)
+		}
+		rv.Set(reflect.ValueOf(v.Object()))
+		return nil
+	}
+
+	if v.IsNull() || v.IsUndefined() {
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return v.decode(rv.Elem())
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return v.decodeError(rv)
+		}
+
+		i, err := v.decodeInterface()
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(&i).Elem())
+		return nil
+
+	case reflect.Bool:
+		if !v.IsBoolean() {
+			return v.decodeError(rv)
+		}
+		rv.SetBool(v.Boolean())
+		return nil
+
+	case reflect.String:
+		if !v.IsString() {
+			return v.decodeError(rv)
+		}
+		rv.SetString(v.String())
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !v.IsNumber() {
+			return v.decodeError(rv)
+		}
+		n := v.Number()
+		// check the range before converting, out of range conversions are implementation-defined
+		if n != math.Trunc(n) || n < -(1<<63) || n >= 1<<63 || rv.OverflowInt(int64(n)) {
+			return fmt.Errorf("cannot decode JS number %v into Go value of type %v", n, rv.Type())
+		}
+		rv.SetInt(int64(n))
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !v.IsNumber() {
+			return v.decodeError(rv)
+		}
+		n := v.Number()
+		if n != math.Trunc(n) || n < 0 || n >= 1<<64 || rv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("cannot decode JS number %v into Go value of type %v", n, rv.Type())
+		}
+		rv.SetUint(uint64(n))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		if !v.IsNumber() {
+			return v.decodeError(rv)
+		}
+		n := v.Number()
+		if rv.OverflowFloat(n) {
+			return fmt.Errorf("cannot decode JS number %v into Go value of type %v", n, rv.Type())
+		}
+		rv.SetFloat(n)
+		return nil
+
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			t, err := v.Time()
+			if err != nil {
+				return err
+			}
+			rv.Set(reflect.ValueOf(t))
+			return nil
+		}
+
+		if !v.IsObject() {
+			return v.decodeError(rv)
+		}
+		return v.decodeStruct(rv)
+
+	case reflect.Map:
+		if !v.IsObject() {
+			return v.decodeError(rv)
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot decode JS object into Go map with key type %v", rv.Type().Key())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+		o := v.Object()
+		et := rv.Type().Elem()
+		for _, name := range o.PropertyNames() {
+			ev := reflect.New(et).Elem()
+			if err := o.Property(name).decode(ev); err != nil {
+				return fmt.Errorf("key %q: %w", name, err)
+			}
+			rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), ev)
+		}
+		return nil
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && v.IsTypedArray() {
+			rv.SetBytes(v.Bytes())
+			return nil
+		}
+
+		if !v.IsArray() {
+			return v.decodeError(rv)
+		}
+
+		o := v.Object()
+		n := o.Length()
+		sl := reflect.MakeSlice(rv.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := o.PropertyAt(i).decode(sl.Index(i)); err != nil {
+				return fmt.Errorf("index %v: %w", i, err)
+			}
+		}
+		rv.Set(sl)
+		return nil
+
+	case reflect.Array:
+		if !v.IsArray() {
+			return v.decodeError(rv)
+		}
+
+		o := v.Object()
+		n := o.Length()
+		for i := 0; i < rv.Len(); i++ {
+			if i >= n {
+				rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+				continue
+			}
+			if err := o.PropertyAt(i).decode(rv.Index(i)); err != nil {
+				return fmt.Errorf("index %v: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	return v.decodeError(rv)
+}
+
+func (v *JSValue) decodeStruct(rv reflect.Value) error {
+	o := v.Object()
+	fields := structFields(rv.Type())
+
+	for _, name := range o.PropertyNames() {
+		var field *structField
+
+		for i := range fields {
+			if fields[i].name == name {
+				field = &fields[i]
+				break
+			}
+		}
+
+		if field == nil {
+			for i := range fields {
+				if strings.EqualFold(fields[i].name, name) {
+					field = &fields[i]
+					break
+				}
+			}
+		}
+
+		if field == nil {
+			continue
+		}
+
+		fv := rv
+		for i, x := range field.index {
+			if i > 0 && fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+
+		if err := o.Property(name).decode(fv); err != nil {
+			return fmt.Errorf("field %v: %w", field.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (v *JSValue) decodeInterface() (interface{}, error) {
+	switch v.Type() {
+	case JSTypeUndefined, JSTypeNull:
+		return nil, nil
+
+	case JSTypeBoolean:
+		return v.Boolean(), nil
+
+	case JSTypeNumber:
+		return v.Number(), nil
+
+	case JSTypeString:
+		return v.String(), nil
+	}
+
+	switch {
+	case v.IsDate():
+		return v.Time()
+
+	case v.IsTypedArray():
+		return v.Bytes(), nil
+
+	case v.IsArray():
+		o := v.Object()
+		n := o.Length()
+		arr := make([]interface{}, n)
+		for i := range arr {
+			e, err := o.PropertyAt(i).decodeInterface()
+			if err != nil {
+				return nil, fmt.Errorf("index %v: %w", i, err)
+			}
+			arr[i] = e
+		}
+		return arr, nil
+
+	case v.IsFunction():
+		return nil, errors.New("cannot decode JS function")
+	}
+
+	o := v.Object()
+	m := map[string]interface{}{}
+	for _, name := range o.PropertyNames() {
+		e, err := o.Property(name).decodeInterface()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", name, err)
+		}
+		m[name] = e
+	}
+	return m, nil
+}
+
+// Time returns the time of a JavaScript Date.
+func (v *JSValue) Time() (time.Time, error) {
+	if !v.IsDate() {
+		return time.Time{}, fmt.Errorf("cannot decode JS %v into time.Time", v.typeName())
+	}
+
+	o := v.Object()
+	ms, err := o.Property("getTime").Object().CallErr(o)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	n := ms.Number()
+	if math.IsNaN(n) {
+		return time.Time{}, errors.New("invalid JS date")
+	}
+
+	return time.UnixMilli(int64(n)), nil
+}
+
+// Tests whether a JavaScript value is a typed array (or ArrayBuffer).
+func (v *JSValue) IsTypedArray() bool {
+	return C.JSValueGetTypedArrayType(v.ctx, v.val, nil) != C.kJSTypedArrayTypeNone
+}
+
+// Bytes returns a copy of the content of a typed array or ArrayBuffer.
+func (v *JSValue) Bytes() []byte {
+	o := v.Object()
+	if o == nil {
+		return nil
+	}
+
+	if C.JSValueGetTypedArrayType(v.ctx, v.val, nil) == C.kJSTypedArrayTypeArrayBuffer {
+		p := C.JSObjectGetArrayBufferBytesPtr(v.ctx, o.obj, nil)
+		n := C.JSObjectGetArrayBufferByteLength(v.ctx, o.obj, nil)
+		return C.GoBytes(p, C.int(n))
+	}
+
+	p := C.JSObjectGetTypedArrayBytesPtr(v.ctx, o.obj, nil)
+	if p == nil {
+		return nil
+	}
+
+	off := C.JSObjectGetTypedArrayByteOffset(v.ctx, o.obj, nil)
+	n := C.JSObjectGetTypedArrayByteLength(v.ctx, o.obj, nil)
+	return C.GoBytes(unsafe.Add(p, off), C.int(n))
+}
+
+// Gets the "length" property of an object (usually an array).
+func (o *JSObject) Length() int {
+	return int(o.Property("length").Number())
+}
+
+// Gets a property from an object by numeric index.
+func (o *JSObject) PropertyAt(i int) *JSValue {
+	return &JSValue{ctx: o.ctx, val: C.JSObjectGetPropertyAtIndex(o.ctx, o.obj, C.uint(i), nil)}
+}
@@ -0,0 +1,234 @@
+package ultralight
+
+import (
+	"context"
+	"sync"
+)
+
+// ViewEvent is an event delivered by View.Events. It's one of:
+// BeginLoadingEvent, FinishLoadingEvent, FailLoadingEvent, UpdateHistoryEvent, DOMReadyEvent,
+// ChangeTitleEvent, ChangeURLEvent, ChangeCursorEvent, ConsoleMessageEvent.
+type ViewEvent interface {
+	viewEvent()
+}
+
+// The page began loading a new URL into a frame.
+type BeginLoadingEvent struct {
+	LoadEvent
+}
+
+// The page finished loading a URL into a frame.
+type FinishLoadingEvent struct {
+	LoadEvent
+}
+
+// An error occurred while loading a URL into a frame.
+type FailLoadingEvent struct {
+	Err *LoadError
+}
+
+// The history (back/forward state) was modified.
+type UpdateHistoryEvent struct{}
+
+// All JavaScript has been parsed and the document is ready.
+type DOMReadyEvent struct {
+	LoadEvent
+}
+
+// The page title changed.
+type ChangeTitleEvent struct {
+	Title string
+}
+
+// The page URL changed.
+type ChangeURLEvent struct {
+	URL string
+}
+
+// The mouse cursor changed.
+type ChangeCursorEvent struct {
+	Cursor Cursor
+}
+
+// A message was added to the console.
+type ConsoleMessageEvent struct {
+	Source   MessageSource
+	Level    MessageLevel
+	Message  string
+	Line     uint
+	Column   uint
+	SourceID string
+}
+
+func (BeginLoadingEvent) viewEvent()   {}
+func (FinishLoadingEvent) viewEvent()  {}
+func (FailLoadingEvent) viewEvent()    {}
+func (UpdateHistoryEvent) viewEvent()  {}
+func (DOMReadyEvent) viewEvent()       {}
+func (ChangeTitleEvent) viewEvent()    {}
+func (ChangeURLEvent) viewEvent()      {}
+func (ChangeCursorEvent) viewEvent()   {}
+func (ConsoleMessageEvent) viewEvent() {}
+
+// WindowEvent is an event delivered by Window.Events. It's one of:
+// ResizeEvent, CloseEvent.
+type WindowEvent interface {
+	windowEvent()
+}
+
+// The window was resized (in device coordinates).
+type ResizeEvent struct {
+	Width, Height uint
+}
+
+// The window was closed.
+type CloseEvent struct{}
+
+func (ResizeEvent) windowEvent() {}
+func (CloseEvent) windowEvent()  {}
+
+// Events returns a channel that receives all the View events.
+// The channel is closed when the View is destroyed.
+func (view *View) Events() <-chan ViewEvent {
+	return view.EventsContext(context.Background())
+}
+
+// EventsContext returns a channel that receives all the View events.
+// The channel is closed when the View is destroyed or ctx is done.
+//
+// Events are queued without blocking the main thread, so the channel
+// should be drained.
+func (view *View) EventsContext(ctx context.Context) <-chan ViewEvent {
+	s := newEventStream[ViewEvent](ctx)
+
+	subs := []*Subscription{
+		view.AddBeginLoadingListener(func(ev LoadEvent) { s.push(BeginLoadingEvent{ev}) }),
+		view.AddFinishLoadingListener(func(ev LoadEvent) { s.push(FinishLoadingEvent{ev}) }),
+		view.AddFailLoadingListener(func(err *LoadError) { s.push(FailLoadingEvent{err}) }),
+		view.AddUpdateHistoryListener(func() { s.push(UpdateHistoryEvent{}) }),
+		view.AddDOMReadyListener(func(ev LoadEvent) { s.push(DOMReadyEvent{ev}) }),
+		view.AddChangeTitleListener(func(title string) { s.push(ChangeTitleEvent{Title: title}) }),
+		view.AddChangeURLListener(func(url string) { s.push(ChangeURLEvent{URL: url}) }),
+		view.AddChangeCursorListener(func(cursor Cursor) { s.push(ChangeCursorEvent{Cursor: cursor}) }),
+		view.AddConsoleMessageListener(func(source MessageSource, level MessageLevel,
+			message string, line uint, col uint, sourceID string) {
+			s.push(ConsoleMessageEvent{Source: source, Level: level, Message: message,
+				Line: line, Column: col, SourceID: sourceID})
+		}),
+		view.addListener(eventDestroy, s.close),
+	}
+
+	go s.run(subs)
+	return s.out
+}
+
+// Events returns a channel that receives all the Window events.
+// The channel is closed when the Window is destroyed.
+func (win *Window) Events() <-chan WindowEvent {
+	return win.EventsContext(context.Background())
+}
+
+// EventsContext returns a channel that receives all the Window events.
+// The channel is closed when the Window is destroyed or ctx is done.
+func (win *Window) EventsContext(ctx context.Context) <-chan WindowEvent {
+	s := newEventStream[WindowEvent](ctx)
+
+	subs := []*Subscription{
+		win.AddResizeListener(func(width, height uint) { s.push(ResizeEvent{Width: width, Height: height}) }),
+		win.AddCloseListener(func() { s.push(CloseEvent{}) }),
+		win.addListener(eventDestroy, s.close),
+	}
+
+	go s.run(subs)
+	return s.out
+}
+
+// eventStream forwards events from the main thread to a channel,
+// using an unbounded queue so that the main thread never blocks.
+type eventStream[T any] struct {
+	ctx    context.Context
+	out    chan T
+	signal chan struct{}
+
+	mu     sync.Mutex
+	queue  []T
+	closed bool
+}
+
+func newEventStream[T any](ctx context.Context) *eventStream[T] {
+	return &eventStream[T]{
+		ctx:    ctx,
+		out:    make(chan T),
+		signal: make(chan struct{}, 1),
+	}
+}
+
+// push queues an event (called on the main thread).
+func (s *eventStream[T]) push(ev T) {
+	s.mu.Lock()
+	if !s.closed {
+		s.queue = append(s.queue, ev)
+	}
+	s.mu.Unlock()
+
+	s.wakeup()
+}
+
+// close closes the stream, after delivering the queued events.
+func (s *eventStream[T]) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.wakeup()
+}
+
+func (s *eventStream[T]) wakeup() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the events until the stream is closed or the context is done,
+// then removes the listeners.
+func (s *eventStream[T]) run(subs []*Subscription) {
+	defer func() {
+		close(s.out)
+
+		// listeners can only be removed on the main thread
+		runOnMain(func() {
+			for _, sub := range subs {
+				sub.Cancel()
+			}
+		})
+	}()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.close()
+			return
+
+		case <-s.signal:
+		}
+
+		s.mu.Lock()
+		queue, closed := s.queue, s.closed
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, ev := range queue {
+			select {
+			case s.out <- ev:
+			case <-s.ctx.Done():
+				s.close()
+				return
+			}
+		}
+
+		if closed {
+			return
+		}
+	}
+}
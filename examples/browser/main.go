@@ -1,14 +1,36 @@
 package main
 
 import (
+	"embed"
+	"io/fs"
+	"os"
+
 	"github.com/raff/ultralight-go"
 )
 
+//go:embed assets
+var assets embed.FS
+
 var (
-	app = ultralight.NewApp()
+	app *ultralight.App
 )
 
+// browserFS serves the embedded assets, falling back to the working directory
+// for everything else (i.e. the SDK resources).
+type browserFS struct{}
+
+func (browserFS) Open(name string) (fs.File, error) {
+	if f, err := assets.Open(name); err == nil {
+		return f, nil
+	}
+
+	return os.DirFS(".").Open(name)
+}
+
 func main() {
+	ultralight.SetFileSystem(browserFS{})
+
+	app = ultralight.NewApp()
 	defer app.Destroy()
 
 	win := app.NewWindow(1024, 768, false, "Ultralight Browser")
@@ -29,11 +29,11 @@ func NewTab(ui *UI, id int, width, height uint, x, y int) *Tab {
 		}
 	})
 
-	view.OnBeginLoading(func() {
+	view.OnBeginLoading(func(ev ultralight.LoadEvent) {
 		ui.UpdateTabNavigation(id, view.IsLoading(), view.CanGoBack(), view.CanGoForward())
 	})
 
-	view.OnFinishLoading(func() {
+	view.OnFinishLoading(func(ev ultralight.LoadEvent) {
 		ui.UpdateTabNavigation(id, view.IsLoading(), view.CanGoBack(), view.CanGoForward())
 	})
 
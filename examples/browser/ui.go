@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/raff/ultralight-go"
@@ -49,29 +50,23 @@ func NewUI(win *ultralight.Window) *UI {
 	view := ovl.View()
 
 	/*
-		view.OnBeginLoading(func() {
+		view.OnBeginLoading(func(ev ultralight.LoadEvent) {
 			fmt.Println("begin loading")
 		})
 
-		view.OnFinishLoading(func() {
+		view.OnFinishLoading(func(ev ultralight.LoadEvent) {
 			view := ovl.View()
 			fmt.Println("finish loading", view.URL())
 		})
 	*/
 
-	view.OnDOMReady(func() {
+	view.OnDOMReady(func(ev ultralight.LoadEvent) {
 		jscontext := view.JSContext()
 		globalObject := jscontext.GlobalObject()
 
-		globalObject.SetPropertyValue("OnBack", ui.OnBack)
-		globalObject.SetPropertyValue("OnForward", ui.OnForward)
-		globalObject.SetPropertyValue("OnRefresh", ui.OnRefresh)
-		globalObject.SetPropertyValue("OnStop", ui.OnStop)
-		//globalObject.SetPropertyValue("OnToggleTools", ui.OnToggleTools)
-		globalObject.SetPropertyValue("OnRequestNewTab", ui.OnRequestNewTab)
-		globalObject.SetPropertyValue("OnRequestTabClose", ui.OnRequestTabClose)
-		globalObject.SetPropertyValue("OnActiveTabChange", ui.OnActiveTabChange)
-		globalObject.SetPropertyValue("OnRequestChangeURL", ui.OnRequestChangeURL)
+		if err := jscontext.Bind("", uiHandlers{ui}); err != nil {
+			fmt.Println("cannot bind UI handlers:", err)
+		}
 
 		ui.updateBack = globalObject.Property("updateBack").Object()
 		ui.updateForward = globalObject.Property("updateForward").Object()
@@ -114,113 +109,114 @@ func (ui *UI) removeTab(i int) {
 	}
 }
 
-func (ui *UI) OnBack(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if ui.activeTab() != nil {
-		ui.activeTab().View().GoBack()
-	}
-	return nil
+// uiHandlers are the Go functions called by ui.html
+type uiHandlers struct {
+	ui *UI
 }
 
-func (ui *UI) OnForward(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if ui.activeTab() != nil {
-		ui.activeTab().View().GoForward()
+func (h uiHandlers) OnBack() {
+	if h.ui.activeTab() != nil {
+		h.ui.activeTab().View().GoBack()
 	}
-	return nil
 }
 
-func (ui *UI) OnRefresh(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if ui.activeTab() != nil {
-		ui.activeTab().View().Reload()
+func (h uiHandlers) OnForward() {
+	if h.ui.activeTab() != nil {
+		h.ui.activeTab().View().GoForward()
 	}
-	return nil
 }
 
-func (ui *UI) OnStop(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if ui.activeTab() != nil {
-		ui.activeTab().View().Stop()
+func (h uiHandlers) OnRefresh() {
+	if h.ui.activeTab() != nil {
+		h.ui.activeTab().View().Reload()
 	}
-	return nil
 }
 
-func (ui *UI) OnRequestNewTab(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	ui.CreateNewTab()
-	return nil
+func (h uiHandlers) OnStop() {
+	if h.ui.activeTab() != nil {
+		h.ui.activeTab().View().Stop()
+	}
 }
 
-func (ui *UI) OnRequestTabClose(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
+func (h uiHandlers) OnRequestNewTab() {
+	h.ui.CreateNewTab()
+}
 
-	if len(args) == 1 {
-		id := int(args[0].Number())
+// the tab id comes from a data attribute, so it's a string
+func (h uiHandlers) OnRequestTabClose(tabId string) error {
+	ui := h.ui
 
-		tab := ui.tabs[id]
-		if tab == nil {
-			return nil
-		}
+	id, err := strconv.Atoi(tabId)
+	if err != nil {
+		return err
+	}
 
-		if len(ui.tabs) == 1 {
-			app.Quit()
-		}
+	tab := ui.tabs[id]
+	if tab == nil {
+		return nil
+	}
 
-		if id != ui.activeTabId {
-			ui.removeTab(id)
-		} else {
-			tab.readyToClose = true
-		}
+	if len(ui.tabs) == 1 {
+		app.Quit()
+	}
 
-		ui.closeTab.Call(nil, id)
+	if id != ui.activeTabId {
+		ui.removeTab(id)
+	} else {
+		tab.readyToClose = true
 	}
 
+	ui.closeTab.Call(nil, id)
 	return nil
 }
 
-func (ui *UI) OnActiveTabChange(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if len(args) == 1 {
-		id := int(args[0].Number())
+func (h uiHandlers) OnActiveTabChange(tabId string) error {
+	ui := h.ui
 
-		if id == ui.activeTabId {
-			return nil
-		}
+	id, err := strconv.Atoi(tabId)
+	if err != nil {
+		return err
+	}
 
-		if ui.tabs[id] == nil {
-			return nil
-		}
+	if id == ui.activeTabId {
+		return nil
+	}
 
-		ui.activeTab().Hide()
-		if ui.activeTab().readyToClose {
-			ui.removeTab(ui.activeTabId)
-		}
+	if ui.tabs[id] == nil {
+		return nil
+	}
 
-		ui.activeTabId = id
-		ui.activeTab().Show()
+	ui.activeTab().Hide()
+	if ui.activeTab().readyToClose {
+		ui.removeTab(ui.activeTabId)
+	}
 
-		view := ui.activeTab().View()
+	ui.activeTabId = id
+	ui.activeTab().Show()
 
-		ui.SetLoading(view.IsLoading())
-		ui.SetCanGoBack(view.CanGoBack())
-		ui.SetCanGoForward(view.CanGoBack())
-		ui.SetURL(view.URL())
-	}
+	view := ui.activeTab().View()
 
+	ui.SetLoading(view.IsLoading())
+	ui.SetCanGoBack(view.CanGoBack())
+	ui.SetCanGoForward(view.CanGoBack())
+	ui.SetURL(view.URL())
 	return nil
 }
 
-func (ui *UI) OnRequestChangeURL(f, this *ultralight.JSObject, args ...*ultralight.JSValue) *ultralight.JSValue {
-	if len(args) == 1 {
-		qurl := args[0].String()
-		if !strings.Contains(qurl, "://") { // no URL scheme
-			if strings.Contains(qurl, ".") { // host/domain
-				qurl = "https://" + qurl
-			} else {
-				qurl = fmt.Sprintf("https://www.google.com/search?q=%v", url.QueryEscape(qurl))
-			}
-		}
+func (h uiHandlers) OnRequestChangeURL(qurl string) {
+	ui := h.ui
 
-		if len(ui.tabs) != 0 {
-			ui.activeTab().View().LoadURL(qurl)
+	if !strings.Contains(qurl, "://") { // no URL scheme
+		if strings.Contains(qurl, ".") { // host/domain
+			qurl = "https://" + qurl
+		} else {
+			qurl = fmt.Sprintf("https://www.google.com/search?q=%v", url.QueryEscape(qurl))
 		}
 	}
 
-	return nil
+	if len(ui.tabs) != 0 {
+		ui.activeTab().View().LoadURL(qurl)
+	}
 }
 
 func (ui *UI) CreateNewTab() {
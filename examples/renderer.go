@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"github.com/raff/ultralight-go"
 )
 
@@ -14,17 +18,13 @@ func main() {
 	v := r.NewView(200, 200, false)
 	defer v.Destroy()
 
-	done := false
-
-	v.OnFinishLoading(func() {
-		r.Render()
-		v.WriteToPNG("result.png")
-		done = true
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	v.LoadHTML("<h1>Hello!</h1><p>Welcome to Ultralight!</p>")
-
-	for !done {
-		r.Update()
+	if err := v.LoadHTMLAndWait(ctx, "<h1>Hello!</h1><p>Welcome to Ultralight!</p>"); err != nil {
+		log.Fatal(err)
 	}
+
+	r.Render()
+	v.WriteToPNG("result.png")
 }
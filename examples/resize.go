@@ -54,21 +54,25 @@ func main() {
 
 	//view := win.View()
 
-	win.View().OnBeginLoading(func() {
-		fmt.Println("begin loading")
+	win.View().OnBeginLoading(func(ev ultralight.LoadEvent) {
+		fmt.Println("begin loading", ev.URL, "main frame:", ev.IsMainFrame)
 	})
 
-	win.View().OnFinishLoading(func() {
+	win.View().OnFinishLoading(func(ev ultralight.LoadEvent) {
 		view := win.View()
 		win.SetTitle(view.Title())
 		fmt.Println("finish loading", view.URL())
 	})
 
+	win.View().OnFailLoading(func(err *ultralight.LoadError) {
+		fmt.Println("fail loading", err)
+	})
+
 	win.View().OnUpdateHistory(func() {
 		fmt.Println("update history")
 	})
 
-	win.View().OnDOMReady(func() {
+	win.View().OnDOMReady(func(ev ultralight.LoadEvent) {
 		fmt.Println("DOM ready")
 
 		fmt.Println("GlobalObject properties:", win.View().JSContext().GlobalObject().PropertyNames(), "\n")
@@ -0,0 +1,123 @@
+package ultralight
+
+/*
+#include <stdlib.h>
+#include <AppCore/CAPI.h>
+
+extern bool fileSystemFileExistsCallback(ULString);
+extern ULString fileSystemGetFileMimeTypeCallback(ULString);
+extern ULString fileSystemGetFileCharsetCallback(ULString);
+extern ULBuffer fileSystemOpenFileCallback(ULString);
+
+static inline void set_platform_file_system() {
+        ULFileSystem fs = { 0 };
+        fs.file_exists = fileSystemFileExistsCallback;
+        fs.get_file_mime_type = fileSystemGetFileMimeTypeCallback;
+        fs.get_file_charset = fileSystemGetFileCharsetCallback;
+        fs.open_file = fileSystemOpenFileCallback;
+        ulPlatformSetFileSystem(fs);
+}
+*/
+import "C"
+import (
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+var platformFS struct {
+	sync.RWMutex
+	fsys fs.FS
+}
+
+// SetFileSystem sets the file system used to load file:/// URLs.
+// Any fs.FS can be used, including embed.FS. If fsys is nil the AppCore
+// file system (rooted at the current directory) is used instead.
+//
+// This should be called before creating the App or Renderer. Note that Ultralight
+// also loads its own resources (see Config.ResourcePath) through the file system.
+func SetFileSystem(fsys fs.FS) {
+	platformFS.Lock()
+	platformFS.fsys = fsys
+	platformFS.Unlock()
+
+	if fsys == nil {
+		enablePlatformFileSystem(".")
+	} else {
+		C.set_platform_file_system()
+	}
+}
+
+// fsPath converts a path from Ultralight to a valid fs.FS path.
+func fsPath(s C.ULString) (string, bool) {
+	p := strings.TrimLeft(decodeULString(s), "/")
+	if p == "" {
+		p = "."
+	}
+
+	p = path.Clean(p)
+	return p, fs.ValidPath(p)
+}
+
+func fileSystem() fs.FS {
+	platformFS.RLock()
+	defer platformFS.RUnlock()
+
+	return platformFS.fsys
+}
+
+//export fileSystemFileExistsCallback
+func fileSystemFileExistsCallback(p C.ULString) C.bool {
+	fsys := fileSystem()
+	name, ok := fsPath(p)
+	if fsys == nil || !ok {
+		return false
+	}
+
+	fi, err := fs.Stat(fsys, name)
+	return C.bool(err == nil && !fi.IsDir())
+}
+
+//export fileSystemGetFileMimeTypeCallback
+func fileSystemGetFileMimeTypeCallback(p C.ULString) C.ULString {
+	name, _ := fsPath(p)
+
+	mtype := mime.TypeByExtension(path.Ext(name))
+	if mtype == "" {
+		mtype = "application/octet-stream"
+	} else if i := strings.IndexByte(mtype, ';'); i >= 0 {
+		mtype = strings.TrimSpace(mtype[:i])
+	}
+
+	// the library takes ownership of the returned string
+	return createULString(mtype)
+}
+
+//export fileSystemGetFileCharsetCallback
+func fileSystemGetFileCharsetCallback(p C.ULString) C.ULString {
+	return createULString("utf-8")
+}
+
+//export fileSystemOpenFileCallback
+func fileSystemOpenFileCallback(p C.ULString) C.ULBuffer {
+	fsys := fileSystem()
+	name, ok := fsPath(p)
+	if fsys == nil || !ok {
+		return nil
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil
+	}
+
+	var ptr unsafe.Pointer
+	if len(data) > 0 {
+		ptr = unsafe.Pointer(&data[0])
+	}
+
+	return C.ulCreateBufferFromCopy(ptr, C.size_t(len(data)))
+}
@@ -0,0 +1,235 @@
+package ultralight
+
+/*
+#include <stdlib.h>
+#include <AppCore/CAPI.h>
+
+extern ULString fontLoaderGetFallbackFontCallback();
+extern ULString fontLoaderGetFallbackFontForCharactersCallback(ULString, int, bool);
+extern ULFontFile fontLoaderLoadCallback(ULString, int, bool);
+
+static inline void set_platform_font_loader() {
+        ULFontLoader loader = { 0 };
+        loader.get_fallback_font = fontLoaderGetFallbackFontCallback;
+        loader.get_fallback_font_for_characters = fontLoaderGetFallbackFontForCharactersCallback;
+        loader.load = fontLoaderLoadCallback;
+        ulPlatformSetFontLoader(loader);
+}
+*/
+import "C"
+import (
+	"io/fs"
+	"strings"
+	"sync"
+	"unicode"
+	"unsafe"
+)
+
+// FontLoader provides the fonts used by Ultralight.
+//
+// Methods may be called from any thread.
+type FontLoader interface {
+	// FallbackFont returns the family used when no other font matches.
+	FallbackFont() string
+
+	// FallbackFontForCharacters returns the family used to render characters
+	// missing from the requested font.
+	FallbackFontForCharacters(chars string, weight int, italic bool) string
+
+	// Load returns the TTF/OTF data for a font (nil if not available).
+	Load(family string, weight int, italic bool) []byte
+}
+
+var platformFontLoader struct {
+	sync.RWMutex
+	loader FontLoader
+}
+
+// SetFontLoader sets the font loader used by Ultralight. If loader is nil
+// the AppCore font loader (using the system fonts) is used instead.
+// This should be called before creating the App or Renderer.
+func SetFontLoader(loader FontLoader) {
+	platformFontLoader.Lock()
+	platformFontLoader.loader = loader
+	platformFontLoader.Unlock()
+
+	if loader == nil {
+		C.ulEnablePlatformFontLoader()
+	} else {
+		C.set_platform_font_loader()
+	}
+}
+
+func fontLoader() FontLoader {
+	platformFontLoader.RLock()
+	defer platformFontLoader.RUnlock()
+
+	return platformFontLoader.loader
+}
+
+//export fontLoaderGetFallbackFontCallback
+func fontLoaderGetFallbackFontCallback() C.ULString {
+	var family string
+
+	if loader := fontLoader(); loader != nil {
+		family = loader.FallbackFont()
+	}
+
+	return createULString(family)
+}
+
+//export fontLoaderGetFallbackFontForCharactersCallback
+func fontLoaderGetFallbackFontForCharactersCallback(chars C.ULString, weight C.int, italic C.bool) C.ULString {
+	var family string
+
+	if loader := fontLoader(); loader != nil {
+		family = loader.FallbackFontForCharacters(decodeULString(chars), int(weight), bool(italic))
+	}
+
+	return createULString(family)
+}
+
+//export fontLoaderLoadCallback
+func fontLoaderLoadCallback(family C.ULString, weight C.int, italic C.bool) C.ULFontFile {
+	loader := fontLoader()
+	if loader == nil {
+		return nil
+	}
+
+	data := loader.Load(decodeULString(family), int(weight), bool(italic))
+	if len(data) == 0 {
+		return nil
+	}
+
+	buf := C.ulCreateBufferFromCopy(unsafe.Pointer(&data[0]), C.size_t(len(data)))
+	return C.ulFontFileCreateFromBuffer(buf)
+}
+
+type fontFace struct {
+	weight int
+	italic bool
+	data   []byte
+}
+
+type fontFallback struct {
+	family string
+	table  *unicode.RangeTable
+}
+
+// FontRegistry is a FontLoader serving in-memory fonts,
+// so that rendering doesn't depend on the fonts installed on the system.
+type FontRegistry struct {
+	mu        sync.RWMutex
+	faces     map[string][]fontFace
+	fallback  string
+	fallbacks []fontFallback
+}
+
+// NewFontRegistry creates an empty FontRegistry with the specified fallback family.
+func NewFontRegistry(fallback string) *FontRegistry {
+	return &FontRegistry{faces: map[string][]fontFace{}, fallback: fallback}
+}
+
+// Register adds the TTF/OTF data for a font family, weight (100-900) and style.
+func (r *FontRegistry) Register(family string, weight int, italic bool, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(family)
+	faces := r.faces[key]
+
+	for i, f := range faces {
+		if f.weight == weight && f.italic == italic {
+			faces[i].data = data
+			return
+		}
+	}
+
+	r.faces[key] = append(faces, fontFace{weight: weight, italic: italic, data: data})
+}
+
+// RegisterFS adds a font file read from fsys (i.e. an embed.FS).
+func (r *FontRegistry) RegisterFS(fsys fs.FS, name, family string, weight int, italic bool) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	r.Register(family, weight, italic, data)
+	return nil
+}
+
+// SetFallbackFont sets the family used when no other font matches.
+func (r *FontRegistry) SetFallbackFont(family string) {
+	r.mu.Lock()
+	r.fallback = family
+	r.mu.Unlock()
+}
+
+// AddFallbackFont sets the family used to render characters in table
+// (i.e. unicode.Han) missing from the requested font.
+// Fallbacks are checked in the order they are added.
+func (r *FontRegistry) AddFallbackFont(family string, table *unicode.RangeTable) {
+	r.mu.Lock()
+	r.fallbacks = append(r.fallbacks, fontFallback{family: family, table: table})
+	r.mu.Unlock()
+}
+
+// FallbackFont implements FontLoader.
+func (r *FontRegistry) FallbackFont() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.fallback
+}
+
+// FallbackFontForCharacters implements FontLoader.
+func (r *FontRegistry) FallbackFontForCharacters(chars string, weight int, italic bool) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range chars {
+		if unicode.IsSpace(c) {
+			continue
+		}
+
+		for _, fb := range r.fallbacks {
+			if unicode.Is(fb.table, c) {
+				return fb.family
+			}
+		}
+
+		break
+	}
+
+	return r.fallback
+}
+
+// Load implements FontLoader, returning the closest registered weight and style.
+// It returns nil for unknown families, so that Ultralight asks for a fallback font
+// (see FallbackFont and FallbackFontForCharacters).
+func (r *FontRegistry) Load(family string, weight int, italic bool) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	faces := r.faces[strings.ToLower(family)]
+
+	var best []byte
+	bestScore := -1
+
+	for _, f := range faces {
+		score := f.weight - weight
+		if score < 0 {
+			score = -score
+		}
+		if f.italic != italic {
+			score += 1000
+		}
+
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = f.data, score
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,70 @@
+package ultralight
+
+/*
+#include <stdint.h>
+*/
+import "C"
+import (
+	"runtime/cgo"
+	"sync"
+)
+
+// callbackHandles keeps track of the Go objects passed to C as callback
+// "user data". Each object is wrapped in a cgo.Handle, and the handle value
+// is what C gets (it is only converted to a pointer on the C side, by the
+// set_* helpers, and back to an integer by the callback trampolines).
+//
+// Handle values are never reused and deleted handles are removed from the
+// registry, so late callbacks with a stale handle resolve to nil instead
+// of a destroyed (or different) Go object.
+var callbackHandles = struct {
+	sync.Mutex
+	handles map[C.uintptr_t]cgo.Handle
+}{
+	handles: map[C.uintptr_t]cgo.Handle{},
+}
+
+// newCallbackData registers v and returns the handle value to pass to C.
+func newCallbackData(v interface{}) C.uintptr_t {
+	h := cgo.NewHandle(v)
+	data := C.uintptr_t(h)
+
+	callbackHandles.Lock()
+	callbackHandles.handles[data] = h
+	callbackHandles.Unlock()
+	return data
+}
+
+// lookupCallbackData returns the object registered for data,
+// or nil if data is not (or no longer) registered.
+func lookupCallbackData(data C.uintptr_t) interface{} {
+	if data == 0 {
+		return nil
+	}
+
+	callbackHandles.Lock()
+	h, ok := callbackHandles.handles[data]
+	callbackHandles.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return h.Value()
+}
+
+// deleteCallbackData unregisters data and releases the associated handle.
+func deleteCallbackData(data C.uintptr_t) {
+	if data == 0 {
+		return
+	}
+
+	callbackHandles.Lock()
+	h, ok := callbackHandles.handles[data]
+	delete(callbackHandles.handles, data)
+	callbackHandles.Unlock()
+
+	if ok {
+		h.Delete()
+	}
+}
@@ -0,0 +1,313 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+#include <stdlib.h>
+*/
+import "C"
+import "unsafe"
+
+type MouseEventType int
+
+const (
+	MouseEventMoved = MouseEventType(C.kMouseEventType_MouseMoved)
+	MouseEventDown  = MouseEventType(C.kMouseEventType_MouseDown)
+	MouseEventUp    = MouseEventType(C.kMouseEventType_MouseUp)
+)
+
+type MouseButton int
+
+const (
+	MouseButtonNone   = MouseButton(C.kMouseButton_None)
+	MouseButtonLeft   = MouseButton(C.kMouseButton_Left)
+	MouseButtonMiddle = MouseButton(C.kMouseButton_Middle)
+	MouseButtonRight  = MouseButton(C.kMouseButton_Right)
+)
+
+type ScrollEventType int
+
+const (
+	ScrollByPixel = ScrollEventType(C.kScrollEventType_ScrollByPixel)
+	ScrollByPage  = ScrollEventType(C.kScrollEventType_ScrollByPage)
+)
+
+type KeyEventType int
+
+const (
+	// Key-Down event type. (Does not trigger accelerator commands in WebCore)
+	//
+	// Note: You should prefer RawKeyDown for non-text keys and Char for text.
+	KeyEventDown = KeyEventType(C.kKeyEventType_KeyDown)
+
+	// Key-Up event type. Use this when a physical key is released.
+	KeyEventUp = KeyEventType(C.kKeyEventType_KeyUp)
+
+	// Raw Key-Down type. Use this when a physical key is pressed.
+	KeyEventRawDown = KeyEventType(C.kKeyEventType_RawKeyDown)
+
+	// Character input event type. Use this when the OS generates text from
+	// a physical key being pressed (eg, WM_CHAR on Windows).
+	KeyEventChar = KeyEventType(C.kKeyEventType_Char)
+)
+
+// KeyModifiers is a bitmask of the modifier keys held during a KeyEvent.
+type KeyModifiers uint
+
+const (
+	ModAltKey   = KeyModifiers(1 << 0)
+	ModCtrlKey  = KeyModifiers(1 << 1)
+	ModMetaKey  = KeyModifiers(1 << 2)
+	ModShiftKey = KeyModifiers(1 << 3)
+)
+
+// KeyCode is a virtual key code (see <Ultralight/KeyCodes.h>).
+type KeyCode int
+
+const (
+	KeyBack     = KeyCode(0x08)
+	KeyTab      = KeyCode(0x09)
+	KeyClear    = KeyCode(0x0C)
+	KeyReturn   = KeyCode(0x0D)
+	KeyShift    = KeyCode(0x10)
+	KeyControl  = KeyCode(0x11)
+	KeyMenu     = KeyCode(0x12)
+	KeyPause    = KeyCode(0x13)
+	KeyCapital  = KeyCode(0x14)
+	KeyEscape   = KeyCode(0x1B)
+	KeySpace    = KeyCode(0x20)
+	KeyPrior    = KeyCode(0x21)
+	KeyNext     = KeyCode(0x22)
+	KeyEnd      = KeyCode(0x23)
+	KeyHome     = KeyCode(0x24)
+	KeyLeft     = KeyCode(0x25)
+	KeyUp       = KeyCode(0x26)
+	KeyRight    = KeyCode(0x27)
+	KeyDown     = KeyCode(0x28)
+	KeySelect   = KeyCode(0x29)
+	KeyPrint    = KeyCode(0x2A)
+	KeyExecute  = KeyCode(0x2B)
+	KeySnapshot = KeyCode(0x2C)
+	KeyInsert   = KeyCode(0x2D)
+	KeyDelete   = KeyCode(0x2E)
+	KeyHelp     = KeyCode(0x2F)
+
+	Key0 = KeyCode(0x30)
+	Key1 = KeyCode(0x31)
+	Key2 = KeyCode(0x32)
+	Key3 = KeyCode(0x33)
+	Key4 = KeyCode(0x34)
+	Key5 = KeyCode(0x35)
+	Key6 = KeyCode(0x36)
+	Key7 = KeyCode(0x37)
+	Key8 = KeyCode(0x38)
+	Key9 = KeyCode(0x39)
+
+	KeyA = KeyCode(0x41)
+	KeyB = KeyCode(0x42)
+	KeyC = KeyCode(0x43)
+	KeyD = KeyCode(0x44)
+	KeyE = KeyCode(0x45)
+	KeyF = KeyCode(0x46)
+	KeyG = KeyCode(0x47)
+	KeyH = KeyCode(0x48)
+	KeyI = KeyCode(0x49)
+	KeyJ = KeyCode(0x4A)
+	KeyK = KeyCode(0x4B)
+	KeyL = KeyCode(0x4C)
+	KeyM = KeyCode(0x4D)
+	KeyN = KeyCode(0x4E)
+	KeyO = KeyCode(0x4F)
+	KeyP = KeyCode(0x50)
+	KeyQ = KeyCode(0x51)
+	KeyR = KeyCode(0x52)
+	KeyS = KeyCode(0x53)
+	KeyT = KeyCode(0x54)
+	KeyU = KeyCode(0x55)
+	KeyV = KeyCode(0x56)
+	KeyW = KeyCode(0x57)
+	KeyX = KeyCode(0x58)
+	KeyY = KeyCode(0x59)
+	KeyZ = KeyCode(0x5A)
+
+	KeyF1  = KeyCode(0x70)
+	KeyF2  = KeyCode(0x71)
+	KeyF3  = KeyCode(0x72)
+	KeyF4  = KeyCode(0x73)
+	KeyF5  = KeyCode(0x74)
+	KeyF6  = KeyCode(0x75)
+	KeyF7  = KeyCode(0x76)
+	KeyF8  = KeyCode(0x77)
+	KeyF9  = KeyCode(0x78)
+	KeyF10 = KeyCode(0x79)
+	KeyF11 = KeyCode(0x7A)
+	KeyF12 = KeyCode(0x7B)
+)
+
+// MouseEvent is a generic mouse event.
+type MouseEvent struct {
+	Type   MouseEventType
+	X, Y   int
+	Button MouseButton
+}
+
+// ScrollEvent is a generic scroll event.
+type ScrollEvent struct {
+	Type           ScrollEventType
+	DeltaX, DeltaY int
+}
+
+// KeyEvent is a generic keyboard event.
+type KeyEvent struct {
+	Type KeyEventType
+
+	// Modifiers currently pressed.
+	Modifiers KeyModifiers
+
+	// The virtual key-code associated with this keyboard event.
+	VirtualKeyCode KeyCode
+
+	// The actual key-code generated by the platform.
+	NativeKeyCode int
+
+	// The actual text generated by this keyboard event
+	// (usually only a single character).
+	Text string
+
+	// The text generated by this keyboard event before all modifiers
+	// except shift are applied.
+	UnmodifiedText string
+
+	// Whether or not this is a keypad event.
+	IsKeypad bool
+
+	// Whether or not this was generated as the result of an auto-repeat
+	// (eg, holding down a key).
+	IsAutoRepeat bool
+
+	// Whether or not the pressed key is a "system key" (eg, Alt+Space on Windows).
+	IsSystemKey bool
+}
+
+// FireMouseEvent fires a mouse event.
+func (view *View) FireMouseEvent(ev MouseEvent) {
+	evt := C.ulCreateMouseEvent(C.ULMouseEventType(ev.Type), C.int(ev.X), C.int(ev.Y), C.ULMouseButton(ev.Button))
+	defer C.ulDestroyMouseEvent(evt)
+
+	C.ulViewFireMouseEvent(view.view, evt)
+}
+
+// FireScrollEvent fires a scroll event.
+func (view *View) FireScrollEvent(ev ScrollEvent) {
+	evt := C.ulCreateScrollEvent(C.ULScrollEventType(ev.Type), C.int(ev.DeltaX), C.int(ev.DeltaY))
+	defer C.ulDestroyScrollEvent(evt)
+
+	C.ulViewFireScrollEvent(view.view, evt)
+}
+
+// FireKeyEvent fires a keyboard event.
+func (view *View) FireKeyEvent(ev KeyEvent) {
+	s := C.CString(ev.Text)
+	text := C.ulCreateString(s)
+	us := C.CString(ev.UnmodifiedText)
+	utext := C.ulCreateString(us)
+
+	defer func() {
+		C.ulDestroyString(text)
+		C.ulDestroyString(utext)
+		C.free(unsafe.Pointer(s))
+		C.free(unsafe.Pointer(us))
+	}()
+
+	evt := C.ulCreateKeyEvent(C.ULKeyEventType(ev.Type), C.uint(ev.Modifiers),
+		C.int(ev.VirtualKeyCode), C.int(ev.NativeKeyCode),
+		text, utext,
+		C.bool(ev.IsKeypad), C.bool(ev.IsAutoRepeat), C.bool(ev.IsSystemKey))
+	defer C.ulDestroyKeyEvent(evt)
+
+	C.ulViewFireKeyEvent(view.view, evt)
+}
+
+// MouseMove moves the mouse to x, y (in device coordinates).
+func (view *View) MouseMove(x, y int) {
+	view.FireMouseEvent(MouseEvent{Type: MouseEventMoved, X: x, Y: y, Button: MouseButtonNone})
+}
+
+// Click moves the mouse to x, y and clicks the left button.
+func (view *View) Click(x, y int) {
+	view.ClickButton(x, y, MouseButtonLeft)
+}
+
+// ClickButton moves the mouse to x, y and clicks the specified button.
+func (view *View) ClickButton(x, y int, button MouseButton) {
+	view.MouseMove(x, y)
+	view.FireMouseEvent(MouseEvent{Type: MouseEventDown, X: x, Y: y, Button: button})
+	view.FireMouseEvent(MouseEvent{Type: MouseEventUp, X: x, Y: y, Button: button})
+}
+
+// Scroll scrolls the page by dx, dy pixels.
+func (view *View) Scroll(dx, dy int) {
+	view.FireScrollEvent(ScrollEvent{Type: ScrollByPixel, DeltaX: dx, DeltaY: dy})
+}
+
+var keyText = map[KeyCode]string{
+	KeyReturn: "\r",
+	KeyTab:    "\t",
+	KeySpace:  " ",
+}
+
+// PressKey presses and releases a key, with the specified modifiers.
+func (view *View) PressKey(key KeyCode, mods KeyModifiers) {
+	text := keyText[key]
+
+	view.FireKeyEvent(KeyEvent{Type: KeyEventRawDown, Modifiers: mods, VirtualKeyCode: key})
+	if text != "" {
+		view.FireKeyEvent(KeyEvent{Type: KeyEventChar, Modifiers: mods, VirtualKeyCode: key,
+			Text: text, UnmodifiedText: text})
+	}
+	view.FireKeyEvent(KeyEvent{Type: KeyEventUp, Modifiers: mods, VirtualKeyCode: key})
+}
+
+// charKey returns the virtual key code and modifiers that generate r
+// on a US keyboard (0 for characters without a dedicated key).
+func charKey(r rune) (KeyCode, KeyModifiers) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return KeyA + KeyCode(r-'a'), 0
+
+	case r >= 'A' && r <= 'Z':
+		return KeyA + KeyCode(r-'A'), ModShiftKey
+
+	case r >= '0' && r <= '9':
+		return Key0 + KeyCode(r-'0'), 0
+
+	case r == ' ':
+		return KeySpace, 0
+	}
+
+	return 0, 0
+}
+
+// TypeText sends the text to the focused element, one character at a time
+// (each character is sent as a key down, char and key up sequence).
+//
+// Newlines are sent as Return key presses.
+func (view *View) TypeText(s string) {
+	for _, r := range s {
+		switch r {
+		case '\n':
+			view.PressKey(KeyReturn, 0)
+
+		case '\t':
+			view.PressKey(KeyTab, 0)
+
+		default:
+			key, mods := charKey(r)
+			text := string(r)
+
+			view.FireKeyEvent(KeyEvent{Type: KeyEventRawDown, Modifiers: mods, VirtualKeyCode: key})
+			view.FireKeyEvent(KeyEvent{Type: KeyEventChar, Modifiers: mods, VirtualKeyCode: key,
+				Text: text, UnmodifiedText: text})
+			view.FireKeyEvent(KeyEvent{Type: KeyEventUp, Modifiers: mods, VirtualKeyCode: key})
+		}
+	}
+}
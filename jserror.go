@@ -0,0 +1,80 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+*/
+import "C"
+import "fmt"
+
+// JSError is a JavaScript exception thrown while evaluating a script
+// or calling a function.
+type JSError struct {
+	// The exception value, as thrown.
+	Value *JSValue
+
+	Name      string
+	Message   string
+	Stack     string
+	Line      int
+	Column    int
+	SourceURL string
+}
+
+func newJSError(ctx C.JSContextRef, exc C.JSValueRef) *JSError {
+	v := &JSValue{ctx: ctx, val: exc}
+	err := &JSError{Value: v}
+
+	if !v.IsObject() {
+		// something like `throw "oops"`
+		err.Message = v.String()
+		return err
+	}
+
+	o := v.Object()
+
+	str := func(name string) string {
+		if p := o.Property(name); !p.IsUndefined() && !p.IsNull() {
+			return p.String()
+		}
+
+		return ""
+	}
+
+	num := func(name string) int {
+		if p := o.Property(name); p.IsNumber() {
+			return int(p.Number())
+		}
+
+		return 0
+	}
+
+	err.Name = str("name")
+	err.Message = str("message")
+	err.Stack = str("stack")
+	err.Line = num("line")
+	err.Column = num("column")
+	err.SourceURL = str("sourceURL")
+
+	if err.Name == "" && err.Message == "" {
+		err.Message = v.String()
+	}
+
+	return err
+}
+
+func (e *JSError) Error() string {
+	msg := e.Message
+	if e.Name != "" {
+		msg = e.Name + ": " + msg
+	}
+
+	if e.Line > 0 {
+		if e.SourceURL != "" {
+			msg = fmt.Sprintf("%v (%v:%v)", msg, e.SourceURL, e.Line)
+		} else {
+			msg = fmt.Sprintf("%v (line %v)", msg, e.Line)
+		}
+	}
+
+	return msg
+}
@@ -0,0 +1,141 @@
+package ultralight
+
+import (
+	"reflect"
+	"sync"
+)
+
+// eventKind identifies a View or Window event.
+type eventKind int
+
+const (
+	eventBeginLoading eventKind = iota
+	eventFinishLoading
+	eventFailLoading
+	eventUpdateHistory
+	eventDOMReady
+	eventChangeTitle
+	eventChangeURL
+	eventChangeCursor
+	eventConsoleMessage
+	eventPaint
+	eventResize
+	eventClose
+
+	// internal: the View or Window is being destroyed
+	eventDestroy
+)
+
+type listener struct {
+	fn interface{}
+}
+
+// listeners holds the callbacks for each kind of event. The callback set
+// with the On* methods is stored as a regular listener, so that there is
+// at most one for each event kind.
+type listeners struct {
+	byKind map[eventKind][]*listener
+	setter map[eventKind]*listener
+
+	// set when the View or Window has been destroyed
+	destroyed bool
+}
+
+// add adds a listener for the event kind.
+func (ls *listeners) add(kind eventKind, fn interface{}) *listener {
+	if ls.byKind == nil {
+		ls.byKind = map[eventKind][]*listener{}
+	}
+
+	l := &listener{fn: fn}
+	ls.byKind[kind] = append(ls.byKind[kind], l)
+	return l
+}
+
+// remove removes a listener for the event kind.
+func (ls *listeners) remove(kind eventKind, l *listener) {
+	list := ls.byKind[kind]
+
+	for i, e := range list {
+		if e == l {
+			ls.byKind[kind] = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+}
+
+// set replaces the listener added by a previous call to set
+// (used by the On* methods). If fn is nil the listener is only removed.
+func (ls *listeners) set(kind eventKind, fn interface{}) {
+	if l := ls.setter[kind]; l != nil {
+		ls.remove(kind, l)
+		delete(ls.setter, kind)
+	}
+
+	if fn == nil || reflect.ValueOf(fn).IsNil() {
+		return
+	}
+
+	if ls.setter == nil {
+		ls.setter = map[eventKind]*listener{}
+	}
+
+	ls.setter[kind] = ls.add(kind, fn)
+}
+
+// has returns true if there are listeners for the event kind.
+func (ls *listeners) has(kind eventKind) bool {
+	return len(ls.byKind[kind]) > 0
+}
+
+// get returns the listener functions for the event kind.
+// The list is a copy, so listeners can be safely added or removed
+// while it's processed.
+func (ls *listeners) get(kind eventKind) []interface{} {
+	list := ls.byKind[kind]
+	if len(list) == 0 {
+		return nil
+	}
+
+	fns := make([]interface{}, len(list))
+	for i, l := range list {
+		fns[i] = l.fn
+	}
+
+	return fns
+}
+
+// clear removes all listeners, after notifying the eventDestroy ones,
+// and marks the listeners as destroyed.
+func (ls *listeners) clear() {
+	for _, cb := range ls.get(eventDestroy) {
+		cb.(func())()
+	}
+
+	ls.byKind = nil
+	ls.setter = nil
+	ls.destroyed = true
+}
+
+// Subscription is returned when adding a listener, and can be used to
+// remove it.
+type Subscription struct {
+	once   sync.Once
+	events *listeners
+	cancel func()
+}
+
+func newSubscription(events *listeners, cancel func()) *Subscription {
+	return &Subscription{events: events, cancel: cancel}
+}
+
+// Cancel removes the listener. It's safe to call more than once, and
+// it does nothing after the View or Window has been destroyed, but as with
+// other View and Window methods it should be called on the main thread.
+func (s *Subscription) Cancel() {
+	s.once.Do(func() {
+		if !s.events.destroyed {
+			s.cancel()
+		}
+	})
+}
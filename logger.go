@@ -0,0 +1,117 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+
+extern void loggerLogMessageCallback(ULLogLevel, ULString);
+
+static inline void set_platform_logger() {
+        ULLogger logger = { 0 };
+        logger.log_message = loggerLogMessageCallback;
+        ulPlatformSetLogger(logger);
+}
+*/
+import "C"
+import (
+	"context"
+	"log"
+	"log/slog"
+	"sync"
+)
+
+type LogLevel int
+
+const (
+	LogLevelError   = LogLevel(C.kLogLevel_Error)
+	LogLevelWarning = LogLevel(C.kLogLevel_Warning)
+	LogLevelInfo    = LogLevel(C.kLogLevel_Info)
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelError:
+		return "ERROR"
+	case LogLevelWarning:
+		return "WARNING"
+	case LogLevelInfo:
+		return "INFO"
+	}
+
+	return "UNKNOWN"
+}
+
+// Logger receives Ultralight internal diagnostics.
+//
+// LogMessage may be called from any thread.
+type Logger interface {
+	LogMessage(level LogLevel, message string)
+}
+
+// LoggerFunc is a function implementing Logger.
+type LoggerFunc func(level LogLevel, message string)
+
+func (f LoggerFunc) LogMessage(level LogLevel, message string) {
+	f(level, message)
+}
+
+var platformLogger struct {
+	sync.RWMutex
+	logger Logger
+}
+
+// SetLogger sets the logger for Ultralight diagnostics (nil discards them).
+// This should be called before creating the App or Renderer.
+func SetLogger(logger Logger) {
+	platformLogger.Lock()
+	platformLogger.logger = logger
+	platformLogger.Unlock()
+
+	// the callback is kept once set, since Ultralight may still call it
+	C.set_platform_logger()
+}
+
+// StdLogger returns a Logger writing to a standard library logger
+// (or the default logger if l is nil).
+func StdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+
+	return LoggerFunc(func(level LogLevel, message string) {
+		l.Printf("ultralight %v: %v", level, message)
+	})
+}
+
+// SlogLogger returns a Logger writing to a structured logger
+// (or the default logger if l is nil).
+func SlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return LoggerFunc(func(level LogLevel, message string) {
+		var sl slog.Level
+
+		switch level {
+		case LogLevelError:
+			sl = slog.LevelError
+		case LogLevelWarning:
+			sl = slog.LevelWarn
+		default:
+			sl = slog.LevelInfo
+		}
+
+		l.Log(context.Background(), sl, message, "source", "ultralight")
+	})
+}
+
+//export loggerLogMessageCallback
+func loggerLogMessageCallback(level C.ULLogLevel, message C.ULString) {
+	platformLogger.RLock()
+	logger := platformLogger.logger
+	platformLogger.RUnlock()
+
+	if logger != nil {
+		logger.LogMessage(LogLevel(level), decodeULString(message))
+	}
+}
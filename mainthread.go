@@ -0,0 +1,138 @@
+package ultralight
+
+/*
+#include <pthread.h>
+
+static pthread_t main_thread;
+
+static inline void set_main_thread() {
+        main_thread = pthread_self();
+}
+
+static inline int is_main_thread() {
+        return pthread_equal(main_thread, pthread_self());
+}
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+func init() {
+	// Ultralight (and the windowing system used by App) expects to be
+	// called always from the same thread, the main one.
+	// Package initialization runs on the main thread, so this keeps the
+	// main goroutine there.
+	runtime.LockOSThread()
+	C.set_main_thread()
+}
+
+// IsMainThread returns true if the caller is running on the main thread,
+// where all View, Window and JavaScript calls should be made.
+func IsMainThread() bool {
+	return C.is_main_thread() != 0
+}
+
+// mainQueue holds the functions that should run on the main (UI) thread,
+// the one calling App.Run or Renderer.Update.
+var mainQueue struct {
+	sync.Mutex
+	funcs []func()
+}
+
+// runOnMain queues f to be executed on the main thread.
+// It's safe to call from any goroutine.
+func runOnMain(f func()) {
+	mainQueue.Lock()
+	mainQueue.funcs = append(mainQueue.funcs, f)
+	mainQueue.Unlock()
+}
+
+// runOnMainSync executes f on the main thread and waits for it to complete.
+// If already on the main thread f is called directly.
+func runOnMainSync(f func() interface{}) interface{} {
+	if IsMainThread() {
+		return f()
+	}
+
+	res := make(chan interface{}, 1)
+	runOnMain(func() {
+		res <- f()
+	})
+
+	return <-res
+}
+
+// runPending executes all the queued functions.
+// It's called by the App update callback and by Renderer.Update.
+func runPending() {
+	mainQueue.Lock()
+	funcs := mainQueue.funcs
+	mainQueue.funcs = nil
+	mainQueue.Unlock()
+
+	for _, f := range funcs {
+		f()
+	}
+}
+
+// currentRenderer is the Renderer used to pump the event loop when waiting
+// for something to happen (there is only one per application lifetime).
+var currentRenderer *Renderer
+
+var errNoRenderer = errors.New("no active Renderer (blocking calls can't be used with App)")
+
+// pumpUntil calls Renderer.Update until done returns true or ctx is done.
+func pumpUntil(ctx context.Context, done func() bool) error {
+	r := currentRenderer
+	if r == nil {
+		return errNoRenderer
+	}
+
+	for !done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.Update()
+
+		if !done() {
+			// don't spin too fast while waiting for network or timers
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
+// Dispatch queues f to run on the main thread, during the next App update.
+// It's safe to call from any goroutine.
+func (app *App) Dispatch(f func()) {
+	runOnMain(f)
+}
+
+// DispatchSync runs f on the main thread, during the next App update,
+// and waits for it to complete, returning its result.
+// It's safe to call from any goroutine (if called from the main thread
+// f is executed immediately).
+func (app *App) DispatchSync(f func() interface{}) interface{} {
+	return runOnMainSync(f)
+}
+
+// Dispatch queues f to run on the main thread, during the next call
+// to Update. It's safe to call from any goroutine.
+func (r *Renderer) Dispatch(f func()) {
+	runOnMain(f)
+}
+
+// DispatchSync runs f on the main thread, during the next call to Update,
+// and waits for it to complete, returning its result.
+// It's safe to call from any goroutine (if called from the main thread
+// f is executed immediately).
+func (r *Renderer) DispatchSync(f func() interface{}) interface{} {
+	return runOnMainSync(f)
+}
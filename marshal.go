@@ -0,0 +1,412 @@
+package ultralight
+
+/*
+#include <AppCore/CAPI.h>
+#include <string.h>
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	jsValueTyp = reflect.TypeOf(JSValue{})
+)
+
+// Marshal converts a Go value to a JavaScript value:
+//
+//   - nil, nil pointers, maps and slices become null
+//   - bool, string and all numeric types become boolean, string and number
+//   - time.Time becomes a Date
+//   - []byte becomes a Uint8Array
+//   - slices and arrays become an Array
+//   - maps with string keys and structs become an Object
+//     (struct fields honour `json` tags, as in encoding/json)
+//   - FunctionCallback and FunctionCallbackErr become a Function
+//   - JSValue and JSObject are returned as is
+//
+// Other types (channels, complex numbers, arbitrary functions) and cyclic
+// data structures return an error.
+func (ctx *JSContext) Marshal(v interface{}) (JSValue, error) {
+	return ctx.marshal(v, map[visit]bool{})
+}
+
+// visit identifies a pointer, map or slice being marshaled, to detect cycles.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (ctx *JSContext) marshal(v interface{}, visiting map[visit]bool) (JSValue, error) {
+	if v == nil {
+		return ctx.Null(), nil
+	}
+
+	switch t := v.(type) {
+	case *JSValue:
+		if t == nil {
+			return ctx.Null(), nil
+		}
+		return *t, nil
+
+	case JSValue:
+		return t, nil
+
+	case *JSObject:
+		if t == nil {
+			return ctx.Null(), nil
+		}
+		return JSValue{ctx: ctx.ctx, val: C.JSValueRef(t.obj)}, nil
+
+	case JSObject:
+		if t.obj == nil {
+			return ctx.Null(), nil
+		}
+		return JSValue{ctx: ctx.ctx, val: C.JSValueRef(t.obj)}, nil
+
+	case FunctionCallback:
+		return *ctx.FunctionCallback("", t), nil
+
+	case func(function, this *JSObject, args ...*JSValue) *JSValue:
+		return *ctx.FunctionCallback("", t), nil
+
+	case FunctionCallbackErr:
+		return *ctx.FunctionCallbackErr("", t), nil
+
+	case func(function, this *JSObject, args ...*JSValue) (*JSValue, error):
+		return *ctx.FunctionCallbackErr("", t), nil
+
+	case time.Time:
+		return ctx.Date(t)
+
+	case []byte:
+		if t == nil {
+			return ctx.Null(), nil
+		}
+		return ctx.Uint8Array(t)
+	}
+
+	return ctx.marshalValue(reflect.ValueOf(v), visiting)
+}
+
+func (ctx *JSContext) marshalValue(rv reflect.Value, visiting map[visit]bool) (JSValue, error) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			break
+		}
+
+		var n int
+		if rv.Kind() == reflect.Slice {
+			n = rv.Len()
+		}
+
+		key := visit{ptr: rv.Pointer(), typ: rv.Type(), len: n}
+		if visiting[key] {
+			return JSValue{}, fmt.Errorf("cycle detected marshaling value of type %v", rv.Type())
+		}
+
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return ctx.Boolean(rv.Bool()), nil
+
+	case reflect.String:
+		return ctx.String(rv.String()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ctx.Number(float64(rv.Int())), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return ctx.Number(float64(rv.Uint())), nil
+
+	case reflect.Float32, reflect.Float64:
+		return ctx.Number(rv.Float()), nil
+
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return ctx.Null(), nil
+		}
+		return ctx.marshal(rv.Elem().Interface(), visiting)
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return ctx.Null(), nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return ctx.Uint8Array(rv.Bytes())
+		}
+		return ctx.marshalArray(rv, visiting)
+
+	case reflect.Array:
+		return ctx.marshalArray(rv, visiting)
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return ctx.Null(), nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return JSValue{}, fmt.Errorf("unsupported map key type %v", rv.Type().Key())
+		}
+
+		obj := ctx.Object()
+		defer protect(ctx.ctx, obj.obj)()
+
+		iter := rv.MapRange()
+		for iter.Next() {
+			jv, err := ctx.marshal(iter.Value().Interface(), visiting)
+			if err != nil {
+				return JSValue{}, fmt.Errorf("key %q: %w", iter.Key().String(), err)
+			}
+
+			obj.SetProperty(iter.Key().String(), &jv)
+		}
+
+		return JSValue{ctx: ctx.ctx, val: C.JSValueRef(obj.obj)}, nil
+
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return ctx.Date(rv.Interface().(time.Time))
+		}
+		if rv.Type() == jsValueTyp {
+			return rv.Interface().(JSValue), nil
+		}
+
+		obj := ctx.Object()
+		defer protect(ctx.ctx, obj.obj)()
+
+		for _, f := range structFields(rv.Type()) {
+			fv, ok := fieldByIndex(rv, f.index)
+			if !ok || (f.omitEmpty && fv.IsZero()) {
+				continue
+			}
+
+			jv, err := ctx.marshal(fv.Interface(), visiting)
+			if err != nil {
+				return JSValue{}, fmt.Errorf("field %v: %w", f.name, err)
+			}
+
+			obj.SetProperty(f.name, &jv)
+		}
+
+		return JSValue{ctx: ctx.ctx, val: C.JSValueRef(obj.obj)}, nil
+	}
+
+	return JSValue{}, fmt.Errorf("unsupported type %v", rv.Type())
+}
+
+func (ctx *JSContext) marshalArray(rv reflect.Value, visiting map[visit]bool) (JSValue, error) {
+	var exc C.JSValueRef
+
+	arr := C.JSObjectMakeArray(ctx.ctx, 0, nil, &exc)
+	if exc != nil {
+		return JSValue{}, newJSError(ctx.ctx, exc)
+	}
+
+	defer protect(ctx.ctx, arr)()
+
+	for i, n := 0, rv.Len(); i < n; i++ {
+		jv, err := ctx.marshal(rv.Index(i).Interface(), visiting)
+		if err != nil {
+			return JSValue{}, fmt.Errorf("index %v: %w", i, err)
+		}
+
+		// store each element right away, so that it's reachable by the garbage collector
+		C.JSObjectSetPropertyAtIndex(ctx.ctx, arr, C.uint(i), jv.val, &exc)
+		if exc != nil {
+			return JSValue{}, newJSError(ctx.ctx, exc)
+		}
+	}
+
+	return JSValue{ctx: ctx.ctx, val: C.JSValueRef(arr)}, nil
+}
+
+// protect keeps a container alive while it's being built, since JavaScriptCore's
+// garbage collector can't see the references held in Go memory.
+// It returns the function that releases it.
+func protect(ctx C.JSContextRef, obj C.JSObjectRef) func() {
+	C.JSValueProtect(ctx, C.JSValueRef(obj))
+
+	return func() {
+		C.JSValueUnprotect(ctx, C.JSValueRef(obj))
+	}
+}
+
+// Creates an empty JavaScript object.
+func (ctx *JSContext) Object() *JSObject {
+	return &JSObject{ctx: ctx.ctx, obj: C.JSObjectMake(ctx.ctx, nil, nil)}
+}
+
+// Creates a JavaScript Date object.
+func (ctx *JSContext) Date(t time.Time) (JSValue, error) {
+	ms := ctx.Number(float64(t.UnixMilli()))
+
+	var exc C.JSValueRef
+
+	date := C.JSObjectMakeDate(ctx.ctx, 1, &ms.val, &exc)
+	if exc != nil {
+		return JSValue{}, newJSError(ctx.ctx, exc)
+	}
+
+	return JSValue{ctx: ctx.ctx, val: C.JSValueRef(date)}, nil
+}
+
+// Creates a JavaScript Uint8Array with a copy of the input bytes.
+func (ctx *JSContext) Uint8Array(b []byte) (JSValue, error) {
+	var exc C.JSValueRef
+
+	arr := C.JSObjectMakeTypedArray(ctx.ctx, C.kJSTypedArrayTypeUint8Array, C.size_t(len(b)), &exc)
+	if exc != nil {
+		return JSValue{}, newJSError(ctx.ctx, exc)
+	}
+
+	if len(b) > 0 {
+		p := C.JSObjectGetTypedArrayBytesPtr(ctx.ctx, arr, &exc)
+		if exc != nil {
+			return JSValue{}, newJSError(ctx.ctx, exc)
+		}
+
+		C.memcpy(p, unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	}
+
+	return JSValue{ctx: ctx.ctx, val: C.JSValueRef(arr)}, nil
+}
+
+// structField describes how a struct field maps to a JavaScript property.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields returns the exported fields of a struct type, using the
+// same naming rules as encoding/json (`json:"name,omitempty"`, `json:"-"`,
+// embedded structs are flattened). When several fields have the same name
+// the shallowest one wins; conflicts at the same depth drop the name,
+// unless exactly one of the fields is tagged.
+func structFields(t reflect.Type) []structField {
+	type candidate struct {
+		structField
+		tagged bool
+	}
+
+	var candidates []candidate
+	visiting := map[reflect.Type]bool{}
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		if visiting[t] {
+			// embedded pointer cycle
+			return
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(tag, ",")
+			idx := append(append([]int{}, index...), i)
+
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, idx)
+					continue
+				}
+			}
+
+			if !sf.IsExported() {
+				continue
+			}
+
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+
+			candidates = append(candidates, candidate{
+				structField: structField{
+					name:      name,
+					index:     idx,
+					omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+				},
+				tagged: tagged,
+			})
+		}
+	}
+
+	walk(t, nil)
+
+	// for each name, find the dominant field (if any)
+	byName := map[string][]candidate{}
+	for _, c := range candidates {
+		byName[c.name] = append(byName[c.name], c)
+	}
+
+	var fields []structField
+
+	for _, c := range candidates {
+		var dominant []candidate
+
+		for _, o := range byName[c.name] {
+			switch {
+			case len(dominant) == 0 || len(o.index) < len(dominant[0].index):
+				dominant = []candidate{o}
+
+			case len(o.index) == len(dominant[0].index):
+				dominant = append(dominant, o)
+			}
+		}
+
+		if len(dominant) > 1 {
+			var tagged []candidate
+			for _, o := range dominant {
+				if o.tagged {
+					tagged = append(tagged, o)
+				}
+			}
+			dominant = tagged
+		}
+
+		if len(dominant) == 1 && reflect.DeepEqual(dominant[0].index, c.index) {
+			fields = append(fields, c.structField)
+		}
+	}
+
+	return fields
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but returns false
+// instead of panicking when going through a nil embedded pointer.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+
+		rv = rv.Field(x)
+	}
+
+	return rv, true
+}
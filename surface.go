@@ -0,0 +1,521 @@
+package ultralight
+
+/*
+#include <stdlib.h>
+#include <AppCore/CAPI.h>
+
+#include <stdint.h>
+
+extern uintptr_t surfaceCreateCallback(unsigned int, unsigned int);
+extern void surfaceDestroyCallback(uintptr_t);
+extern unsigned int surfaceGetWidthCallback(uintptr_t);
+extern unsigned int surfaceGetHeightCallback(uintptr_t);
+extern unsigned int surfaceGetRowBytesCallback(uintptr_t);
+extern size_t surfaceGetSizeCallback(uintptr_t);
+extern void *surfaceLockPixelsCallback(uintptr_t);
+extern void surfaceUnlockPixelsCallback(uintptr_t);
+extern void surfaceResizeCallback(uintptr_t, unsigned int, unsigned int);
+
+// The surface user data is a cgo.Handle value (see handles.go):
+// these convert it from/to an integer for the Go callbacks.
+
+static inline void *surface_create(unsigned int width, unsigned int height) {
+        return (void *)surfaceCreateCallback(width, height);
+}
+
+static inline void surface_destroy(void *data) {
+        surfaceDestroyCallback((uintptr_t)data);
+}
+
+static inline unsigned int surface_get_width(void *data) {
+        return surfaceGetWidthCallback((uintptr_t)data);
+}
+
+static inline unsigned int surface_get_height(void *data) {
+        return surfaceGetHeightCallback((uintptr_t)data);
+}
+
+static inline unsigned int surface_get_row_bytes(void *data) {
+        return surfaceGetRowBytesCallback((uintptr_t)data);
+}
+
+static inline size_t surface_get_size(void *data) {
+        return surfaceGetSizeCallback((uintptr_t)data);
+}
+
+static inline void *surface_lock_pixels(void *data) {
+        return surfaceLockPixelsCallback((uintptr_t)data);
+}
+
+static inline void surface_unlock_pixels(void *data) {
+        surfaceUnlockPixelsCallback((uintptr_t)data);
+}
+
+static inline void surface_resize(void *data, unsigned int width, unsigned int height) {
+        surfaceResizeCallback((uintptr_t)data, width, height);
+}
+
+static inline uintptr_t surface_data(ULSurface surface) {
+        return (uintptr_t)ulSurfaceGetUserData(surface);
+}
+
+static inline void set_platform_surface_definition() {
+        ULSurfaceDefinition def = { 0 };
+        def.create = surface_create;
+        def.destroy = surface_destroy;
+        def.get_width = surface_get_width;
+        def.get_height = surface_get_height;
+        def.get_row_bytes = surface_get_row_bytes;
+        def.get_size = surface_get_size;
+        def.lock_pixels = surface_lock_pixels;
+        def.unlock_pixels = surface_unlock_pixels;
+        def.resize = surface_resize;
+        ulPlatformSetSurfaceDefinition(def);
+}
+*/
+import "C"
+import (
+	"image"
+	"sync"
+	"unsafe"
+)
+
+// SurfaceFactory is notified of the surfaces Ultralight creates for views,
+// when registered with SetSurfaceFactory.
+//
+// Methods are called from the thread driving the Renderer.
+type SurfaceFactory interface {
+	// SurfaceCreated is called when a new surface is created (i.e. by NewView).
+	SurfaceCreated(s *Surface)
+
+	// SurfaceDestroyed is called before the surface pixels are released.
+	SurfaceDestroyed(s *Surface)
+
+	// SurfacePainted is called by Renderer.Render after the surface has been painted,
+	// with the area that changed since the dirty bounds were last cleared.
+	// The surface is not locked: use LockPixels to access the pixels.
+	SurfacePainted(s *Surface, dirty image.Rectangle)
+}
+
+// Surface is a BGRA pixel buffer (4 bytes per pixel, premultiplied alpha) that a View paints into.
+//
+// The pixels are allocated outside of the Go heap and exposed as a slice
+// without copying, so they are only valid until the surface is resized or destroyed.
+//
+// While the pixels are locked (see LockPixels) the geometry getters (Width, Height,
+// RowBytes, Size) and DirtyBounds/ClearDirtyBounds can still be called, but not
+// Image or LockPixels, that would deadlock.
+type Surface struct {
+	// pixel lock, held while Ultralight paints or LockPixels is in effect
+	mu sync.Mutex
+
+	// state guards the fields below. They are only changed holding both mu and
+	// state (in this order), so holding either one is enough to read them.
+	state sync.RWMutex
+
+	width, height, rowBytes uint
+	pixels                  unsafe.Pointer
+	painted                 bool
+
+	surface C.ULSurface
+	cbData  C.uintptr_t
+}
+
+var platformSurfaces struct {
+	sync.Mutex
+	factory  SurfaceFactory
+	surfaces map[*Surface]struct{}
+}
+
+// SetSurfaceFactory makes the views paint into Surfaces, notifying factory
+// of the surface changes. If factory is nil the notifications stop, but the
+// views keep painting into Surfaces: the default bitmap surfaces can't be restored.
+// This should be called before creating the Renderer.
+//
+// Note that View.Bitmap and View.Image are not available with custom surfaces: use View.Surface instead.
+func SetSurfaceFactory(factory SurfaceFactory) {
+	platformSurfaces.Lock()
+	platformSurfaces.factory = factory
+	platformSurfaces.Unlock()
+
+	// the callbacks are kept once set, since Ultralight may still call them
+	C.set_platform_surface_definition()
+}
+
+func surfaceFactory() SurfaceFactory {
+	platformSurfaces.Lock()
+	defer platformSurfaces.Unlock()
+
+	return platformSurfaces.factory
+}
+
+// bindSurface associates the view ULSurface to its Go Surface, to access the dirty bounds.
+func bindSurface(view C.ULView) {
+	if s := surfaceFor(view); s != nil {
+		s.state.Lock()
+		s.surface = C.ulViewGetSurface(view)
+		s.state.Unlock()
+	}
+}
+
+// surfaceFor returns the Go Surface for a view (nil if the view uses a default surface).
+func surfaceFor(view C.ULView) *Surface {
+	if view == nil {
+		return nil
+	}
+
+	surface := C.ulViewGetSurface(view)
+	if surface == nil {
+		return nil
+	}
+
+	s, _ := lookupCallbackData(C.surface_data(surface)).(*Surface)
+	return s
+}
+
+// Surface returns the surface the view paints into, if SetSurfaceFactory was called (nil otherwise).
+func (view *View) Surface() *Surface {
+	return surfaceFor(view.view)
+}
+
+// Get the surface width, in pixels.
+func (s *Surface) Width() uint {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	return s.width
+}
+
+// Get the surface height, in pixels.
+func (s *Surface) Height() uint {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	return s.height
+}
+
+// Get the number of bytes between each row of pixels.
+func (s *Surface) RowBytes() uint {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	return s.rowBytes
+}
+
+// Get the size in bytes of the pixel buffer.
+func (s *Surface) Size() uint {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	return s.rowBytes * s.height
+}
+
+// Lock the pixel buffer, so that it's not painted, resized or released while in use.
+// The lock is not reentrant.
+func (s *Surface) LockPixels() {
+	s.mu.Lock()
+}
+
+// Unlock the pixel buffer.
+func (s *Surface) UnlockPixels() {
+	s.mu.Unlock()
+}
+
+// Pixels returns the pixel buffer, without copying.
+// The surface should be locked (see LockPixels) while the pixels are accessed.
+func (s *Surface) Pixels() []byte {
+	if s.pixels == nil {
+		return nil
+	}
+
+	return unsafe.Slice((*byte)(s.pixels), int(s.rowBytes*s.height))
+}
+
+// Image returns a copy of the surface pixels (converted to RGBA).
+func (s *Surface) Image() *image.RGBA {
+	s.LockPixels()
+	defer s.UnlockPixels()
+
+	img := image.NewRGBA(image.Rect(0, 0, int(s.width), int(s.height)))
+	pixels := s.Pixels()
+
+	for y := 0; y < int(s.height); y++ {
+		src := pixels[y*int(s.rowBytes):]
+		dst := img.Pix[y*img.Stride:]
+
+		for x := 0; x < int(s.width)*4; x += 4 {
+			dst[x+0] = src[x+2]
+			dst[x+1] = src[x+1]
+			dst[x+2] = src[x+0]
+			dst[x+3] = src[x+3]
+		}
+	}
+
+	return img
+}
+
+// DirtyBounds returns the area that changed since the dirty bounds were last cleared.
+func (s *Surface) DirtyBounds() image.Rectangle {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	return s.dirtyBounds()
+}
+
+// ClearDirtyBounds resets the dirty bounds, once the changes have been consumed.
+func (s *Surface) ClearDirtyBounds() {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	if s.surface != nil {
+		C.ulSurfaceClearDirtyBounds(s.surface)
+	}
+}
+
+func (s *Surface) dirtyBounds() image.Rectangle {
+	if s.surface == nil {
+		return image.Rect(0, 0, int(s.width), int(s.height))
+	}
+
+	return intRect(C.ulSurfaceGetDirtyBounds(s.surface))
+}
+
+func intRect(r C.ULIntRect) image.Rectangle {
+	return image.Rect(int(r.left), int(r.top), int(r.right), int(r.bottom))
+}
+
+// resize (re)allocates the pixel buffer. Both mu and state must be held.
+func (s *Surface) resize(width, height uint) {
+	s.width, s.height, s.rowBytes = width, height, width*4
+
+	if s.pixels != nil {
+		C.free(s.pixels)
+		s.pixels = nil
+	}
+
+	if size := s.rowBytes * s.height; size > 0 {
+		s.pixels = C.calloc(1, C.size_t(size))
+	}
+}
+
+// notifySurfacesPainted reports the surfaces painted by the last Render to the SurfaceFactory.
+func notifySurfacesPainted() {
+	platformSurfaces.Lock()
+	factory := platformSurfaces.factory
+	var painted []*Surface
+
+	for s := range platformSurfaces.surfaces {
+		s.state.Lock()
+		if s.painted {
+			s.painted = false
+			painted = append(painted, s)
+		}
+		s.state.Unlock()
+	}
+	platformSurfaces.Unlock()
+
+	if factory == nil {
+		return
+	}
+
+	for _, s := range painted {
+		factory.SurfacePainted(s, s.DirtyBounds())
+	}
+}
+
+func surfaceFromData(data C.uintptr_t) *Surface {
+	s, _ := lookupCallbackData(data).(*Surface)
+	return s
+}
+
+//export surfaceCreateCallback
+func surfaceCreateCallback(width, height C.uint) C.uintptr_t {
+	s := &Surface{}
+	s.resize(uint(width), uint(height))
+	s.cbData = newCallbackData(s)
+
+	platformSurfaces.Lock()
+	if platformSurfaces.surfaces == nil {
+		platformSurfaces.surfaces = map[*Surface]struct{}{}
+	}
+	platformSurfaces.surfaces[s] = struct{}{}
+	factory := platformSurfaces.factory
+	platformSurfaces.Unlock()
+
+	if factory != nil {
+		factory.SurfaceCreated(s)
+	}
+
+	return s.cbData
+}
+
+//export surfaceDestroyCallback
+func surfaceDestroyCallback(data C.uintptr_t) {
+	s := surfaceFromData(data)
+	if s == nil {
+		return
+	}
+
+	platformSurfaces.Lock()
+	delete(platformSurfaces.surfaces, s)
+	factory := platformSurfaces.factory
+	platformSurfaces.Unlock()
+
+	if factory != nil {
+		factory.SurfaceDestroyed(s)
+	}
+
+	s.mu.Lock()
+	s.state.Lock()
+	s.resize(0, 0)
+	s.surface = nil
+	s.state.Unlock()
+	s.mu.Unlock()
+
+	deleteCallbackData(data)
+}
+
+//export surfaceGetWidthCallback
+func surfaceGetWidthCallback(data C.uintptr_t) C.uint {
+	if s := surfaceFromData(data); s != nil {
+		return C.uint(s.Width())
+	}
+
+	return 0
+}
+
+//export surfaceGetHeightCallback
+func surfaceGetHeightCallback(data C.uintptr_t) C.uint {
+	if s := surfaceFromData(data); s != nil {
+		return C.uint(s.Height())
+	}
+
+	return 0
+}
+
+//export surfaceGetRowBytesCallback
+func surfaceGetRowBytesCallback(data C.uintptr_t) C.uint {
+	if s := surfaceFromData(data); s != nil {
+		return C.uint(s.RowBytes())
+	}
+
+	return 0
+}
+
+//export surfaceGetSizeCallback
+func surfaceGetSizeCallback(data C.uintptr_t) C.size_t {
+	if s := surfaceFromData(data); s != nil {
+		return C.size_t(s.Size())
+	}
+
+	return 0
+}
+
+//export surfaceLockPixelsCallback
+func surfaceLockPixelsCallback(data C.uintptr_t) unsafe.Pointer {
+	s := surfaceFromData(data)
+	if s == nil {
+		return nil
+	}
+
+	// the lock is held until surfaceUnlockPixelsCallback
+	s.mu.Lock()
+	return s.pixels
+}
+
+//export surfaceUnlockPixelsCallback
+func surfaceUnlockPixelsCallback(data C.uintptr_t) {
+	if s := surfaceFromData(data); s != nil {
+		s.state.Lock()
+		s.painted = true
+		s.state.Unlock()
+		s.mu.Unlock()
+	}
+}
+
+//export surfaceResizeCallback
+func surfaceResizeCallback(data C.uintptr_t, width, height C.uint) {
+	if s := surfaceFromData(data); s != nil {
+		s.mu.Lock()
+		s.state.Lock()
+		s.resize(uint(width), uint(height))
+		s.state.Unlock()
+		s.mu.Unlock()
+	}
+}
+
+// paintViews are the views with paint listeners.
+var paintViews struct {
+	sync.Mutex
+	views map[*View]struct{}
+}
+
+func setPaintView(view *View, enabled bool) {
+	paintViews.Lock()
+	defer paintViews.Unlock()
+
+	if !enabled {
+		delete(paintViews.views, view)
+		return
+	}
+
+	if paintViews.views == nil {
+		paintViews.views = map[*View]struct{}{}
+	}
+
+	paintViews.views[view] = struct{}{}
+}
+
+// notifyViewsPainted calls the paint listeners of the views painted by the last Render.
+func notifyViewsPainted() {
+	paintViews.Lock()
+	views := make([]*View, 0, len(paintViews.views))
+	for view := range paintViews.views {
+		views = append(views, view)
+	}
+	paintViews.Unlock()
+
+	for _, view := range views {
+		dirty := view.DirtyBounds()
+		if dirty.Empty() {
+			continue
+		}
+
+		view.ClearDirtyBounds()
+
+		for _, cb := range view.events.get(eventPaint) {
+			cb.(func(image.Rectangle))(dirty)
+		}
+	}
+}
+
+// Whether the view has changes that will be painted by the next Renderer.Render.
+func (view *View) NeedsPaint() bool {
+	return bool(C.ulViewGetNeedsPaint(view.view))
+}
+
+// DirtyBounds returns the area of the view surface that changed since the dirty bounds were last cleared
+// (empty if the view has no surface, i.e. with the GPU renderer).
+func (view *View) DirtyBounds() image.Rectangle {
+	if view.view == nil {
+		return image.Rectangle{}
+	}
+
+	surface := C.ulViewGetSurface(view.view)
+	if surface == nil {
+		return image.Rectangle{}
+	}
+
+	return intRect(C.ulSurfaceGetDirtyBounds(surface))
+}
+
+// ClearDirtyBounds resets the dirty bounds of the view surface, once the changes have been consumed.
+func (view *View) ClearDirtyBounds() {
+	if view.view == nil {
+		return
+	}
+
+	if surface := C.ulViewGetSurface(view.view); surface != nil {
+		C.ulSurfaceClearDirtyBounds(surface)
+	}
+}
@@ -4,125 +4,215 @@ package ultralight
 #cgo CFLAGS: -I./SDK/include
 #cgo LDFLAGS: -L./SDK/bin -lUltralight -lUltralightCore -lWebCore -lAppCore -Wl,-rpath,./SDK/bin
 #include <AppCore/CAPI.h>
+#include <stdint.h>
 #include <stdlib.h>
 
-extern void appUpdateCallback(void *);
-extern void winResizeCallback(void *, unsigned int, unsigned int);
-extern void winCloseCallback(void *);
-extern void viewBeginLoadingCallback(void *, ULView);
-extern void viewFinishLoadingCallback(void *, ULView);
-extern void viewUpdateHistoryCallback(void *, ULView);
-extern void viewDOMReadyCallback(void *, ULView);
-extern void viewChangeTitleCallback(void *, ULView, ULString);
-extern void viewChangeURLCallback(void *, ULView, ULString);
-extern void viewChangeCursorCallback(void *, ULView, ULCursor);
-extern void viewConsoleMessageCallback(void* user_data, ULView caller,
-                                       ULMessageSource source, ULMessageLevel level,
-                                       ULString message, unsigned int line_number,
-                                       unsigned int column_number,
-                                       ULString source_id);
+extern void appUpdateCallback(uintptr_t);
+extern void winResizeCallback(uintptr_t, ULWindow, unsigned int, unsigned int);
+extern void winCloseCallback(uintptr_t, ULWindow);
+extern void viewBeginLoadingCallback(uintptr_t, ULView, unsigned long long, bool, ULString);
+extern void viewFinishLoadingCallback(uintptr_t, ULView, unsigned long long, bool, ULString);
+extern void viewFailLoadingCallback(uintptr_t, ULView, unsigned long long, bool, ULString, ULString,
+                                    ULString, int);
+extern void viewUpdateHistoryCallback(uintptr_t, ULView);
+extern void viewDOMReadyCallback(uintptr_t, ULView, unsigned long long, bool, ULString);
+extern void viewChangeTitleCallback(uintptr_t, ULView, ULString);
+extern void viewChangeURLCallback(uintptr_t, ULView, ULString);
+extern void viewChangeCursorCallback(uintptr_t, ULView, ULCursor);
+extern void viewConsoleMessageCallback(uintptr_t, ULView, ULMessageSource, ULMessageLevel, ULString,
+                                       unsigned int, unsigned int, ULString);
 
 extern JSValueRef objFunctionCallback(JSContextRef ctx, JSObjectRef function, JSObjectRef thisObject,
                                       size_t argumentCount, JSValueRef *arguments, JSValueRef* exception);
 
-static inline void set_app_update_callback(ULApp app, void *data) {
-        if (data == NULL) {
+// The user data of the callbacks is a cgo.Handle value (see handles.go):
+// these convert it back to an integer before calling into Go.
+
+static inline void app_update_callback(void *data) {
+        appUpdateCallback((uintptr_t)data);
+}
+
+static inline void win_resize_callback(void *data, ULWindow window, unsigned int width,
+                                       unsigned int height) {
+        winResizeCallback((uintptr_t)data, window, width, height);
+}
+
+static inline void win_close_callback(void *data, ULWindow window) {
+        winCloseCallback((uintptr_t)data, window);
+}
+
+static inline void view_begin_loading_callback(void *data, ULView caller,
+                                               unsigned long long frame_id, bool is_main_frame,
+                                               ULString url) {
+        viewBeginLoadingCallback((uintptr_t)data, caller, frame_id, is_main_frame, url);
+}
+
+static inline void view_finish_loading_callback(void *data, ULView caller,
+                                                unsigned long long frame_id, bool is_main_frame,
+                                                ULString url) {
+        viewFinishLoadingCallback((uintptr_t)data, caller, frame_id, is_main_frame, url);
+}
+
+static inline void view_fail_loading_callback(void *data, ULView caller, unsigned long long frame_id,
+                                              bool is_main_frame, ULString url, ULString description,
+                                              ULString error_domain, int error_code) {
+        viewFailLoadingCallback((uintptr_t)data, caller, frame_id, is_main_frame, url, description,
+                                error_domain, error_code);
+}
+
+static inline void view_update_history_callback(void *data, ULView caller) {
+        viewUpdateHistoryCallback((uintptr_t)data, caller);
+}
+
+static inline void view_dom_ready_callback(void *data, ULView caller, unsigned long long frame_id,
+                                           bool is_main_frame, ULString url) {
+        viewDOMReadyCallback((uintptr_t)data, caller, frame_id, is_main_frame, url);
+}
+
+static inline void view_change_title_callback(void *data, ULView caller, ULString title) {
+        viewChangeTitleCallback((uintptr_t)data, caller, title);
+}
+
+static inline void view_change_url_callback(void *data, ULView caller, ULString url) {
+        viewChangeURLCallback((uintptr_t)data, caller, url);
+}
+
+static inline void view_change_cursor_callback(void *data, ULView caller, ULCursor cursor) {
+        viewChangeCursorCallback((uintptr_t)data, caller, cursor);
+}
+
+static inline void view_console_message_callback(void *data, ULView caller, ULMessageSource source,
+                                                 ULMessageLevel level, ULString message,
+                                                 unsigned int line_number,
+                                                 unsigned int column_number, ULString source_id) {
+        viewConsoleMessageCallback((uintptr_t)data, caller, source, level, message, line_number,
+                                   column_number, source_id);
+}
+
+static inline void set_app_update_callback(ULApp app, uintptr_t data) {
+        if (data == 0) {
             ulAppSetUpdateCallback(app, NULL, NULL);
         } else {
-            ulAppSetUpdateCallback(app, appUpdateCallback, data);
+            ulAppSetUpdateCallback(app, app_update_callback, (void *)data);
         }
 }
 
-static inline void set_win_resize_callback(ULWindow win, void *data) {
-        if (data == NULL) {
+static inline void set_win_resize_callback(ULWindow win, uintptr_t data) {
+        if (data == 0) {
             ulWindowSetResizeCallback(win, NULL, NULL);
         } else {
-            ulWindowSetResizeCallback(win, winResizeCallback, data);
+            ulWindowSetResizeCallback(win, win_resize_callback, (void *)data);
         }
 }
 
-static inline void set_win_close_callback(ULWindow win, void *data) {
-        if (data == NULL) {
+static inline void set_win_close_callback(ULWindow win, uintptr_t data) {
+        if (data == 0) {
             ulWindowSetCloseCallback(win, NULL, NULL);
         } else {
-            ulWindowSetCloseCallback(win, winCloseCallback, data);
+            ulWindowSetCloseCallback(win, win_close_callback, (void *)data);
         }
 }
 
-static inline void set_view_begin_loading_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_begin_loading_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetBeginLoadingCallback(view, NULL, NULL);
         } else {
-            ulViewSetBeginLoadingCallback(view, viewBeginLoadingCallback, data);
+            ulViewSetBeginLoadingCallback(view, view_begin_loading_callback, (void *)data);
         }
 }
 
-static inline void set_view_finish_loading_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_finish_loading_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetFinishLoadingCallback(view, NULL, NULL);
         } else {
-            ulViewSetFinishLoadingCallback(view, viewFinishLoadingCallback, data);
+            ulViewSetFinishLoadingCallback(view, view_finish_loading_callback, (void *)data);
         }
 }
 
-static inline void set_view_update_history_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_fail_loading_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
+            ulViewSetFailLoadingCallback(view, NULL, NULL);
+        } else {
+            ulViewSetFailLoadingCallback(view, view_fail_loading_callback, (void *)data);
+        }
+}
+
+static inline void set_view_update_history_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetUpdateHistoryCallback(view, NULL, NULL);
         } else {
-            ulViewSetUpdateHistoryCallback(view, viewUpdateHistoryCallback, data);
+            ulViewSetUpdateHistoryCallback(view, view_update_history_callback, (void *)data);
         }
 }
 
-static inline void set_view_dom_ready_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_dom_ready_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetDOMReadyCallback(view, NULL, NULL);
         } else {
-            ulViewSetDOMReadyCallback(view, viewDOMReadyCallback, data);
+            ulViewSetDOMReadyCallback(view, view_dom_ready_callback, (void *)data);
         }
 }
 
-static inline void set_view_change_title_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_change_title_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetChangeTitleCallback(view, NULL, NULL);
         } else {
-            ulViewSetChangeTitleCallback(view, viewChangeTitleCallback, data);
+            ulViewSetChangeTitleCallback(view, view_change_title_callback, (void *)data);
         }
 }
 
-static inline void set_view_change_url_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_change_url_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetChangeURLCallback(view, NULL, NULL);
         } else {
-            ulViewSetChangeURLCallback(view, viewChangeURLCallback, data);
+            ulViewSetChangeURLCallback(view, view_change_url_callback, (void *)data);
         }
 }
 
-static inline void set_view_change_cursor_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_change_cursor_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetChangeCursorCallback(view, NULL, NULL);
         } else {
-            ulViewSetChangeCursorCallback(view, viewChangeCursorCallback, data);
+            ulViewSetChangeCursorCallback(view, view_change_cursor_callback, (void *)data);
         }
 }
 
-static inline void set_view_console_message_callback(ULView view, void *data) {
-        if (data == NULL) {
+static inline void set_view_console_message_callback(ULView view, uintptr_t data) {
+        if (data == 0) {
             ulViewSetAddConsoleMessageCallback(view, NULL, NULL);
         } else {
-            ulViewSetAddConsoleMessageCallback(view, viewConsoleMessageCallback, data);
+            ulViewSetAddConsoleMessageCallback(view, view_console_message_callback, (void *)data);
         }
 }
 
-static inline JSObjectRef make_function_callback(JSContextRef ctx, JSStringRef name) {
-        return JSObjectMakeFunctionWithCallback(ctx, name, (JSObjectCallAsFunctionCallback)objFunctionCallback);
+extern void objFinalizeCallback(JSObjectRef object);
+
+static JSClassRef function_class = NULL;
+
+static inline JSObjectRef make_function_callback(JSContextRef ctx, uintptr_t data) {
+        if (function_class == NULL) {
+            JSClassDefinition def = kJSClassDefinitionEmpty;
+            def.className = "GoFunction";
+            def.callAsFunction = (JSObjectCallAsFunctionCallback)objFunctionCallback;
+            def.finalize = (JSObjectFinalizeCallback)objFinalizeCallback;
+            function_class = JSClassCreate(&def);
+        }
+
+        return JSObjectMake(ctx, function_class, (void *)data);
+}
+
+static inline uintptr_t function_callback_data(JSObjectRef function) {
+        return (uintptr_t)JSObjectGetPrivate(function);
 }
 */
 import "C"
 import "unsafe"
+import "image"
 import "unicode/utf16"
 import "unicode/utf8"
 import "reflect"
 import "bytes"
+import "fmt"
 
 import "log"
 
@@ -215,20 +305,24 @@ const (
 // App is the main application object
 type App struct {
 	app     C.ULApp
+	main    *Window
+	view    viewOptions
+	bgra    bool
 	windows map[C.ULWindow]*Window
+	cbData  C.uintptr_t
 
 	onUpdate func()
 }
 
 // Window is an application window
 type Window struct {
-	win C.ULWindow
-	ovl []Overlay
+	win    C.ULWindow
+	ovl    []*Overlay
+	cbData C.uintptr_t
 
 	app *App
 
-	onResize func(width, height uint)
-	onClose  func()
+	events listeners
 }
 
 type Overlay struct {
@@ -238,16 +332,41 @@ type Overlay struct {
 
 // View is the window "content"
 type View struct {
-	view C.ULView
+	view   C.ULView
+	bgra   bool
+	cbData C.uintptr_t
 
-	onBeginLoading   func()
-	onFinishLoading  func()
-	onUpdateHistory  func()
-	onDOMReady       func()
-	onChangeTitle    func(string)
-	onChangeURL      func(string)
-	onChangeCursor   func(Cursor)
-	onConsoleMessage func(MessageSource, MessageLevel, string, uint, uint, string)
+	events listeners
+}
+
+// LoadEvent describes a frame load, for the loading callbacks.
+type LoadEvent struct {
+	// Unique identifier of the frame (the main frame and each iframe).
+	FrameID uint64
+
+	// Whether the frame is the main frame of the View.
+	IsMainFrame bool
+
+	// The URL being loaded.
+	URL string
+}
+
+// LoadError describes a failed frame load, for OnFailLoading.
+type LoadError struct {
+	LoadEvent
+
+	// Human readable description of the error.
+	Description string
+
+	// Name of the module that triggered the error.
+	Domain string
+
+	// Internal error code generated by the module.
+	Code int
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("cannot load %v: %v (%v %v)", e.URL, e.Description, e.Domain, e.Code)
 }
 
 // JSContext
@@ -298,7 +417,7 @@ func decodeULString(s C.ULString) string {
 	}
 
 	data := C.ulStringGetData(s)
-	return decodeUTF16(data, l)
+	return C.GoStringN(data, C.int(l))
 }
 
 func decodeJSString(s C.JSStringRef) string {
@@ -311,30 +430,44 @@ func decodeJSString(s C.JSStringRef) string {
 	return decodeUTF16((*C.ULChar16)(data), l)
 }
 
-// NewApp creates the App singleton.
+// NewApp creates the App singleton, with the default config.
 //
 // Note: You should only create one of these per application lifetime.
 func NewApp() *App {
-	return &App{app: C.ulCreateApp(C.ulCreateSettings(), C.ulCreateConfig()), windows: map[C.ULWindow]*Window{}}
+	c := NewConfig()
+	defer c.Destroy()
+
+	return NewAppConfig(c)
+}
+
+// NewAppConfig creates the App singleton, with the specified config.
+// The config can be destroyed after the App has been created.
+//
+// Note: You should only create one of these per application lifetime.
+func NewAppConfig(c *Config) *App {
+	app := &App{app: C.ulCreateApp(C.ulCreateSettings(), c.cfg), view: c.view, bgra: c.bgra,
+		windows: map[C.ULWindow]*Window{}}
+
+	// the update callback is always set, to run pending main thread tasks
+	app.cbData = newCallbackData(app)
+	C.set_app_update_callback(app.app, app.cbData)
+	return app
 }
 
 // Destroy destroys the App instance.
 func (app *App) Destroy() {
+	C.set_app_update_callback(app.app, 0)
+	deleteCallbackData(app.cbData)
 	C.ulDestroyApp(app.app)
 	app.app = nil
+	app.main = nil
 	app.windows = nil
 }
 
-// Window gets the main application window.
+// Window gets the main application window (the last one created with NewWindow),
+// or nil if there are no windows.
 func (app *App) Window() *Window {
-	ulwin := C.ulAppGetWindow(app.app)
-	if win, ok := app.windows[ulwin]; ok {
-		return win
-	}
-
-	win := &Window{win: ulwin}
-	app.windows[ulwin] = win
-	return win
+	return app.main
 }
 
 // IsRunning checks whether or not the App is running.
@@ -346,15 +479,6 @@ func (app *App) IsRunning() bool {
 // You should update all app logic here.
 func (app *App) OnUpdate(cb func()) {
 	app.onUpdate = cb
-	p := unsafe.Pointer(app.app)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_app_update_callback(app.app, nil)
-	} else {
-		callbackData[p] = app
-		C.set_app_update_callback(app.app, p)
-	}
 }
 
 // Run runs the main loop.
@@ -367,9 +491,6 @@ func (app *App) Quit() {
 	C.ulAppQuit(app.app)
 }
 
-var callbackData = map[unsafe.Pointer]interface{}{}
-var reverseCallbback = map[interface{}]unsafe.Pointer{}
-
 // NewWindow create a new window and sets it as the main application window.
 func (app *App) NewWindow(width, height uint, fullscreen bool, title string) *Window {
 	win := &Window{win: C.ulCreateWindow(C.ulAppGetMainMonitor(app.app),
@@ -378,7 +499,8 @@ func (app *App) NewWindow(width, height uint, fullscreen bool, title string) *Wi
 		C.kWindowFlags_Titled|C.kWindowFlags_Resizable|C.kWindowFlags_Maximizable),
 		app: app}
 
-	C.ulAppSetWindow(app.app, win.win)
+	app.main = win
+	app.windows[win.win] = win
 
 	win.SetTitle(title)
 	win.NewOverlay(width, height, 0, 0)
@@ -387,16 +509,32 @@ func (app *App) NewWindow(width, height uint, fullscreen bool, title string) *Wi
 
 // Destroy destroys the window.
 func (win *Window) Destroy() {
-	delete(win.app.windows, win.win)
+	if win.app != nil {
+		delete(win.app.windows, win.win)
+		if win.app.main == win {
+			win.app.main = nil
+		}
+	}
 	for _, o := range win.ovl {
 		o.Destroy()
 	}
+	win.events.clear()
+	win.updateCallback(eventResize)
+	win.updateCallback(eventClose)
+	deleteCallbackData(win.cbData)
 	C.ulDestroyWindow(win.win)
-	win.OnResize(nil)
+	win.cbData = 0
 	win.ovl = nil
 	win.win = nil
 	win.app = nil
+}
+
+func (win *Window) callbackData() C.uintptr_t {
+	if win.cbData == 0 {
+		win.cbData = newCallbackData(win)
+	}
 
+	return win.cbData
 }
 
 // Close closes the window.
@@ -426,17 +564,24 @@ func (win *Window) Height() uint {
 }
 
 // Create a new Overlay.
+// The overlay View is created with the view options of the App Config (see NewAppConfig).
 func (win *Window) NewOverlay(width, height uint, x, y int) *Overlay {
-	cOvl := C.ulCreateOverlay(win.win, C.uint(width), C.uint(height), C.int(x), C.int(y))
-	ovl := Overlay{ovl: cOvl, view: View{view: C.ulOverlayGetView(cOvl)}}
+	// AppCore renders the windows on the GPU
+	vc := win.app.view.viewConfig(true, false)
+	defer C.ulDestroyViewConfig(vc)
+
+	view := C.ulCreateView(C.ulAppGetRenderer(win.app.app), C.uint(width), C.uint(height), vc, nil)
+	cOvl := C.ulCreateOverlayWithView(win.win, view, C.int(x), C.int(y))
+	ovl := &Overlay{ovl: cOvl, view: View{view: view, bgra: win.app.bgra}}
+
 	win.ovl = append(win.ovl, ovl)
-	return &ovl
+	return ovl
 }
 
 func (win *Window) RemoveOverlay(i int) {
 	if i >= 0 && i < len(win.ovl) {
 		win.ovl[i].Destroy()
-		win.ovl = append(win.ovl[i:], win.ovl[i+1:]...)
+		win.ovl = append(win.ovl[:i], win.ovl[i+1:]...)
 	}
 }
 
@@ -445,7 +590,7 @@ func (win *Window) NOverlay() int {
 }
 
 func (win *Window) Overlay(i int) *Overlay {
-	return &win.ovl[i]
+	return win.ovl[i]
 }
 
 // IsFullscreen checks whether or not a window is fullscreen.
@@ -492,29 +637,57 @@ func (win *Window) Resize(width, height uint) {
 // OnResize sets a callback to be notified when a window resizes
 // (parameters are passed back in device coordinates).
 func (win *Window) OnResize(cb func(width, height uint)) {
-	win.onResize = cb
-	p := unsafe.Pointer(win.win)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_win_resize_callback(win.win, nil)
-	} else {
-		callbackData[p] = win
-		C.set_win_resize_callback(win.win, p)
-	}
+	win.events.set(eventResize, cb)
+	win.updateCallback(eventResize)
 }
 
 // OnClose sets a callback to be notified when a window closes.
 func (win *Window) OnClose(cb func()) {
-	win.onClose = cb
-	p := unsafe.Pointer(win.win)
+	win.events.set(eventClose, cb)
+	win.updateCallback(eventClose)
+}
+
+// AddResizeListener adds a callback to be notified when a window resizes,
+// in addition to the one set with OnResize.
+func (win *Window) AddResizeListener(cb func(width, height uint)) *Subscription {
+	return win.addListener(eventResize, cb)
+}
+
+// AddCloseListener adds a callback to be notified when a window closes,
+// in addition to the one set with OnClose.
+func (win *Window) AddCloseListener(cb func()) *Subscription {
+	return win.addListener(eventClose, cb)
+}
+
+func (win *Window) addListener(kind eventKind, cb interface{}) *Subscription {
+	l := win.events.add(kind, cb)
+	win.updateCallback(kind)
+
+	return newSubscription(&win.events, func() {
+		win.events.remove(kind, l)
+		if win.win != nil {
+			win.updateCallback(kind)
+		}
+	})
+}
+
+// updateCallback sets or removes the C callback, depending on the listeners.
+func (win *Window) updateCallback(kind eventKind) {
+	if win.win == nil {
+		return
+	}
 
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_win_close_callback(win.win, nil)
-	} else {
-		callbackData[p] = win
-		C.set_win_close_callback(win.win, p)
+	var data C.uintptr_t
+	if win.events.has(kind) {
+		data = win.callbackData()
+	}
+
+	switch kind {
+	case eventResize:
+		C.set_win_resize_callback(win.win, data)
+
+	case eventClose:
+		C.set_win_close_callback(win.win, data)
 	}
 }
 
@@ -560,14 +733,22 @@ func (view *View) URL() string {
 
 // Title returns the current title.
 func (view *View) Title() string {
-	s := C.ulViewGetTitle(view.view)
-	l := C.ulStringGetLength(s)
-	if l == 0 {
-		return ""
-	}
+	return decodeULString(C.ulViewGetTitle(view.view))
+}
 
-	data := C.ulStringGetData(s)
-	return decodeUTF16(data, l)
+// Width returns the width, in device coordinates.
+func (view *View) Width() uint {
+	return uint(C.ulViewGetWidth(view.view))
+}
+
+// Height returns the height, in device coordinates.
+func (view *View) Height() uint {
+	return uint(C.ulViewGetHeight(view.view))
+}
+
+// Resize resizes the View (dimensions should be specified in device coordinates).
+func (view *View) Resize(width, height uint) {
+	C.ulViewResize(view.view, C.uint(width), C.uint(height))
 }
 
 // IsLoading Checks if main frame is loading.
@@ -576,21 +757,48 @@ func (view *View) IsLoading() bool {
 }
 
 // JSContext gets the page's JSContext for use with JavaScriptCore API
+// (from the main thread only).
 func (view *View) JSContext() *JSContext {
-	return &JSContext{ctx: C.ulViewGetJSContext(view.view)}
+	return &JSContext{ctx: view.jsContext()}
+}
+
+// jsContext returns the page's JSContextRef.
+//
+// The context lock only keeps other threads from changing the JavaScript state,
+// and the context is always used from the main thread, so it's released right away.
+func (view *View) jsContext() C.JSContextRef {
+	ctx := C.ulViewLockJSContext(view.view)
+	C.ulViewUnlockJSContext(view.view)
+	return ctx
 }
 
 // EvaluateScript evaluates a raw string of JavaScript and return result
+// (undefined if the script throws an exception, see EvaluateScriptErr).
 func (view *View) EvaluateScript(script string) *JSValue {
-	s := C.CString(script)
-	uls := C.ulCreateString(s)
+	ret, err := view.EvaluateScriptErr(script)
+	if err != nil {
+		ctx := view.jsContext()
+		return &JSValue{val: C.JSValueMakeUndefined(ctx), ctx: ctx}
+	}
 
-	defer func() {
-		C.ulDestroyString(uls)
-		C.free(unsafe.Pointer(s))
-	}()
+	return ret
+}
+
+// EvaluateScriptErr evaluates a raw string of JavaScript and return result,
+// or a *JSError if the script throws an exception.
+func (view *View) EvaluateScriptErr(script string) (*JSValue, error) {
+	ctx := view.jsContext()
+	js := makeJSString(script)
+	defer C.JSStringRelease(js)
 
-	return &JSValue{val: C.ulViewEvaluateScript(view.view, uls), ctx: C.ulViewGetJSContext(view.view)}
+	var exc C.JSValueRef
+
+	ret := C.JSEvaluateScript(ctx, js, nil, nil, 1, &exc)
+	if exc != nil {
+		return nil, newJSError(ctx, exc)
+	}
+
+	return &JSValue{val: ret, ctx: ctx}, nil
 }
 
 // CanGoBack checks if can navigate backwards in history
@@ -628,118 +836,175 @@ func (view *View) Stop() {
 	C.ulViewStop(view.view)
 }
 
-// Set callback for when the page begins loading new URL into main frame
-func (view *View) OnBeginLoading(cb func()) {
-	view.onBeginLoading = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_begin_loading_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_begin_loading_callback(view.view, p)
-	}
+// Set callback for when the page begins loading a new URL into a frame
+func (view *View) OnBeginLoading(cb func(ev LoadEvent)) {
+	view.events.set(eventBeginLoading, cb)
+	view.updateCallback(eventBeginLoading)
 }
 
-// Set callback for when the page finishes loading new URL into main frame
-func (view *View) OnFinishLoading(cb func()) {
-	view.onFinishLoading = cb
-	p := unsafe.Pointer(view.view)
+// Set callback for when the page finishes loading a URL into a frame
+func (view *View) OnFinishLoading(cb func(ev LoadEvent)) {
+	view.events.set(eventFinishLoading, cb)
+	view.updateCallback(eventFinishLoading)
+}
 
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_finish_loading_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_finish_loading_callback(view.view, p)
-	}
+// Set callback for when an error occurs while loading a URL into a frame
+func (view *View) OnFailLoading(cb func(err *LoadError)) {
+	view.events.set(eventFailLoading, cb)
+	view.updateCallback(eventFailLoading)
 }
 
 // Set callback for when the history (back/forward state) is modified
 func (view *View) OnUpdateHistory(cb func()) {
-	view.onUpdateHistory = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_update_history_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_update_history_callback(view.view, p)
-	}
+	view.events.set(eventUpdateHistory, cb)
+	view.updateCallback(eventUpdateHistory)
 }
 
 // Set callback for when all JavaScript has been parsed and the document is
 // ready. This is the best time to make initial JavaScript calls to your page.
-func (view *View) OnDOMReady(cb func()) {
-	view.onDOMReady = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_dom_ready_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_dom_ready_callback(view.view, p)
-	}
+func (view *View) OnDOMReady(cb func(ev LoadEvent)) {
+	view.events.set(eventDOMReady, cb)
+	view.updateCallback(eventDOMReady)
 }
 
 // Set callback for when the page title changes
 func (view *View) OnChangeTitle(cb func(string)) {
-	view.onChangeTitle = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_change_title_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_change_title_callback(view.view, p)
-	}
+	view.events.set(eventChangeTitle, cb)
+	view.updateCallback(eventChangeTitle)
 }
 
 // Set callback for when the page URL changes
 func (view *View) OnChangeURL(cb func(string)) {
-	view.onChangeURL = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_change_url_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_change_url_callback(view.view, p)
-	}
+	view.events.set(eventChangeURL, cb)
+	view.updateCallback(eventChangeURL)
 }
 
 // Set callback for when the mouse cursor changes
 func (view *View) OnChangeCursor(cb func(Cursor)) {
-	view.onChangeCursor = cb
-	p := unsafe.Pointer(view.view)
-
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_change_cursor_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_change_cursor_callback(view.view, p)
-	}
+	view.events.set(eventChangeCursor, cb)
+	view.updateCallback(eventChangeCursor)
 }
 
 // Set callback for when a message is added to the console (useful for
 // JavaScript / network errors and debugging)
 func (view *View) OnConsoleMessage(cb func(source MessageSource, level MessageLevel,
 	message string, line uint, col uint, sourceID string)) {
-	view.onConsoleMessage = cb
-	p := unsafe.Pointer(view.view)
+	view.events.set(eventConsoleMessage, cb)
+	view.updateCallback(eventConsoleMessage)
+}
+
+// Set callback for when the view has been painted by Renderer.Render, with the area that changed
+// (the dirty bounds are cleared after the callbacks are called).
+func (view *View) OnPaint(cb func(dirty image.Rectangle)) {
+	view.events.set(eventPaint, cb)
+	view.updateCallback(eventPaint)
+}
+
+// Add a listener for when the page begins loading a new URL into a frame.
+// Unlike OnBeginLoading, any number of listeners can be added.
+func (view *View) AddBeginLoadingListener(cb func(ev LoadEvent)) *Subscription {
+	return view.addListener(eventBeginLoading, cb)
+}
+
+// Add a listener for when the page finishes loading a URL into a frame.
+func (view *View) AddFinishLoadingListener(cb func(ev LoadEvent)) *Subscription {
+	return view.addListener(eventFinishLoading, cb)
+}
+
+// Add a listener for when an error occurs while loading a URL into a frame.
+func (view *View) AddFailLoadingListener(cb func(err *LoadError)) *Subscription {
+	return view.addListener(eventFailLoading, cb)
+}
+
+// Add a listener for when the history (back/forward state) is modified.
+func (view *View) AddUpdateHistoryListener(cb func()) *Subscription {
+	return view.addListener(eventUpdateHistory, cb)
+}
+
+// Add a listener for when the document is ready.
+func (view *View) AddDOMReadyListener(cb func(ev LoadEvent)) *Subscription {
+	return view.addListener(eventDOMReady, cb)
+}
+
+// Add a listener for when the page title changes.
+func (view *View) AddChangeTitleListener(cb func(string)) *Subscription {
+	return view.addListener(eventChangeTitle, cb)
+}
+
+// Add a listener for when the page URL changes.
+func (view *View) AddChangeURLListener(cb func(string)) *Subscription {
+	return view.addListener(eventChangeURL, cb)
+}
+
+// Add a listener for when the mouse cursor changes.
+func (view *View) AddChangeCursorListener(cb func(Cursor)) *Subscription {
+	return view.addListener(eventChangeCursor, cb)
+}
 
-	if cb == nil {
-		callbackData[p] = nil
-		C.set_view_console_message_callback(view.view, nil)
-	} else {
-		callbackData[p] = view
-		C.set_view_console_message_callback(view.view, p)
+// Add a listener for when a message is added to the console.
+func (view *View) AddConsoleMessageListener(cb func(source MessageSource, level MessageLevel,
+	message string, line uint, col uint, sourceID string)) *Subscription {
+	return view.addListener(eventConsoleMessage, cb)
+}
+
+// Add a listener for when the view has been painted.
+func (view *View) AddPaintListener(cb func(dirty image.Rectangle)) *Subscription {
+	return view.addListener(eventPaint, cb)
+}
+
+func (view *View) addListener(kind eventKind, cb interface{}) *Subscription {
+	l := view.events.add(kind, cb)
+	view.updateCallback(kind)
+
+	return newSubscription(&view.events, func() {
+		view.events.remove(kind, l)
+		if view.view != nil {
+			view.updateCallback(kind)
+		}
+	})
+}
+
+// updateCallback sets or removes the C callback, depending on the listeners.
+func (view *View) updateCallback(kind eventKind) {
+	if view.view == nil {
+		return
+	}
+
+	var data C.uintptr_t
+	if view.events.has(kind) {
+		data = view.callbackData()
+	}
+
+	switch kind {
+	case eventBeginLoading:
+		C.set_view_begin_loading_callback(view.view, data)
+
+	case eventFinishLoading:
+		C.set_view_finish_loading_callback(view.view, data)
+
+	case eventFailLoading:
+		C.set_view_fail_loading_callback(view.view, data)
+
+	case eventUpdateHistory:
+		C.set_view_update_history_callback(view.view, data)
+
+	case eventDOMReady:
+		C.set_view_dom_ready_callback(view.view, data)
+
+	case eventChangeTitle:
+		C.set_view_change_title_callback(view.view, data)
+
+	case eventChangeURL:
+		C.set_view_change_url_callback(view.view, data)
+
+	case eventChangeCursor:
+		C.set_view_change_cursor_callback(view.view, data)
+
+	case eventConsoleMessage:
+		C.set_view_console_message_callback(view.view, data)
+
+	case eventPaint:
+		setPaintView(view, data != 0)
 	}
 }
 
@@ -855,74 +1120,85 @@ func (ctx *JSContext) String(v string) JSValue {
 	return JSValue{ctx: ctx.ctx, val: C.JSValueMakeString(ctx.ctx, js)}
 }
 
+// JSValue converts a Go value to a JavaScript value (see Marshal).
+// Values that cannot be converted are logged and returned as undefined.
 func (ctx *JSContext) JSValue(v interface{}) JSValue {
-	if v == nil {
-		return ctx.Null()
+	jv, err := ctx.Marshal(v)
+	if err != nil {
+		log.Printf("cannot convert %T to JSValue: %v", v, err)
+		return ctx.Undefined()
 	}
 
-	switch t := v.(type) {
-	case *JSValue:
-		return *t
-
-	case JSValue:
-		return t
-
-	case bool:
-		return ctx.Boolean(t)
-
-	case string:
-		return ctx.String(t)
-
-	case float64:
-		return ctx.Number(t)
+	return jv
+}
 
-	case float32:
-		return ctx.Number(float64(t))
+func makeJSString(v string) C.JSStringRef {
+	s := C.CString(v)
+	defer C.free(unsafe.Pointer(s))
+	return C.JSStringCreateWithUTF8CString(s)
+}
 
-	case int:
-		return ctx.Number(float64(t))
+type FunctionCallback func(function, this *JSObject, args ...*JSValue) *JSValue
 
-	case int8:
-		return ctx.Number(float64(t))
+// Convenience method for creating a JavaScript function with a given callback as its implementation.
+func (ctx *JSContext) FunctionCallback(name string, cb FunctionCallback) *JSValue {
+	return ctx.makeFunction(name, cb)
+}
 
-	case int16:
-		return ctx.Number(float64(t))
+// FunctionCallbackErr is a FunctionCallback that can fail.
+// A non-nil error is thrown into the calling page as a JavaScript Error.
+type FunctionCallbackErr func(function, this *JSObject, args ...*JSValue) (*JSValue, error)
 
-	case int32:
-		return ctx.Number(float64(t))
+// Convenience method for creating a JavaScript function with a given callback as its implementation.
+// Errors returned by the callback are thrown as JavaScript exceptions.
+func (ctx *JSContext) FunctionCallbackErr(name string, cb FunctionCallbackErr) *JSValue {
+	return ctx.makeFunction(name, cb)
+}
 
-	case int64:
-		return ctx.Number(float64(t))
+// makeFunction creates a function object calling cb.
+// The callback data is released when the object is garbage collected.
+func (ctx *JSContext) makeFunction(name string, cb interface{}) *JSValue {
+	obj := &JSObject{ctx: ctx.ctx, obj: C.make_function_callback(ctx.ctx, newCallbackData(cb))}
 
-	case FunctionCallback:
-		fv := ctx.FunctionCallback("", t)
-		return *fv
+	// make it look like a regular function (call, apply, bind, etc.)
+	if proto := ctx.GlobalObject().Property("Function").Object(); proto != nil {
+		C.JSObjectSetPrototype(ctx.ctx, obj.obj, proto.Property("prototype").val)
+	}
 
-	case func(function, this *JSObject, args ...*JSValue) *JSValue:
-		fv := ctx.FunctionCallback("", t)
-		return *fv
+	if name != "" {
+		// the inherited Function.prototype.name is read-only, so it can only
+		// be shadowed with Object.defineProperty
+		defer protect(ctx.ctx, obj.obj)()
 
-	default:
-		log.Fatalf("cannot convert %#T to JSValue", t)
+		if object := ctx.GlobalObject().Property("Object").Object(); object != nil {
+			desc := map[string]interface{}{"value": name, "configurable": true}
+			if _, err := object.Property("defineProperty").Object().CallErr(object, obj, "name", desc); err != nil {
+				log.Printf("cannot set function name %q: %v", name, err)
+			}
+		}
 	}
 
-	return ctx.Undefined() // not reached
+	return obj.Value()
 }
 
-func makeJSString(v string) C.JSStringRef {
-	s := C.CString(v)
-	defer C.free(unsafe.Pointer(s))
-	return C.JSStringCreateWithUTF8CString(s)
+// Creates a JavaScript Error object with the specified message.
+func (ctx *JSContext) Error(message string) *JSValue {
+	msg := ctx.String(message)
+	args := (*C.JSValueRef)(C.malloc(C.size_t(unsafe.Sizeof(uintptr(0)))))
+	defer C.free(unsafe.Pointer(args))
+
+	*args = msg.val
+	return &JSValue{ctx: ctx.ctx, val: C.JSValueRef(C.JSObjectMakeError(ctx.ctx, 1, args, nil))}
 }
 
-type FunctionCallback func(function, this *JSObject, args ...*JSValue) *JSValue
+// errorValue converts a Go error to the JavaScript value to throw.
+func (ctx *JSContext) errorValue(err error) C.JSValueRef {
+	if jerr, ok := err.(*JSError); ok && jerr.Value != nil {
+		// re-throw the original exception
+		return jerr.Value.val
+	}
 
-// Convenience method for creating a JavaScript function with a given callback as its implementation.
-func (ctx *JSContext) FunctionCallback(name string, cb FunctionCallback) *JSValue {
-	obj := C.make_function_callback(ctx.ctx, makeJSString(name))
-	p := unsafe.Pointer(obj)
-	callbackData[p] = cb
-	return &JSValue{ctx: ctx.ctx, val: C.JSValueRef(obj)}
+	return ctx.Error(err.Error()).val
 }
 
 // Gets the global object of a JavaScript execution context.
@@ -935,6 +1211,11 @@ func (ctx *JSContext) GlobalContext() JSGlobalContext {
 	return JSGlobalContext{ctx: C.JSContextGetGlobalContext(ctx.ctx)}
 }
 
+// Returns the object as a JavaScript value.
+func (o *JSObject) Value() *JSValue {
+	return &JSValue{ctx: o.ctx, val: C.JSValueRef(o.obj)}
+}
+
 // Tests whether an object can be called as a function.
 func (o *JSObject) IsFunction() bool {
 	return bool(C.JSObjectIsFunction(o.ctx, o.obj))
@@ -942,6 +1223,30 @@ func (o *JSObject) IsFunction() bool {
 
 // Calls an object as a function.
 func (o *JSObject) Call(this *JSObject, args ...interface{}) *JSValue {
+	ret, _, err := o.call(this, args...)
+	if err != nil {
+		log.Printf("cannot call function: %v", err)
+		return &JSValue{ctx: o.ctx, val: C.JSValueMakeUndefined(o.ctx)}
+	}
+
+	return &JSValue{ctx: o.ctx, val: ret}
+}
+
+// Calls an object as a function, returning a *JSError if the function
+// throws an exception.
+func (o *JSObject) CallErr(this *JSObject, args ...interface{}) (*JSValue, error) {
+	ret, exc, err := o.call(this, args...)
+	if err != nil {
+		return nil, err
+	}
+	if exc != nil {
+		return nil, newJSError(o.ctx, exc)
+	}
+
+	return &JSValue{ctx: o.ctx, val: ret}, nil
+}
+
+func (o *JSObject) call(this *JSObject, args ...interface{}) (C.JSValueRef, C.JSValueRef, error) {
 	var thisObj C.JSObjectRef
 	var jargs *C.JSValueRef
 
@@ -963,12 +1268,19 @@ func (o *JSObject) Call(this *JSObject, args ...interface{}) *JSValue {
 		ctx := &JSContext{ctx: o.ctx}
 
 		for i, v := range args {
-			ja[i] = ctx.JSValue(v).val
+			jv, err := ctx.Marshal(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("argument %v: %w", i, err)
+			}
+
+			ja[i] = jv.val
 		}
 	}
 
-	ret := C.JSObjectCallAsFunction(o.ctx, o.obj, thisObj, C.size_t(nargs), jargs, nil)
-	return &JSValue{ctx: o.ctx, val: ret}
+	var exc C.JSValueRef
+
+	ret := C.JSObjectCallAsFunction(o.ctx, o.obj, thisObj, C.size_t(nargs), jargs, &exc)
+	return ret, exc, nil
 }
 
 // Sets a property on an object.
@@ -1005,137 +1317,226 @@ func (o *JSObject) SetPropertyValue(name string, value interface{}) {
 }
 
 //export appUpdateCallback
-func appUpdateCallback(userData unsafe.Pointer) {
-	app := callbackData[userData].(*App)
-	if app != nil {
+func appUpdateCallback(userData C.uintptr_t) {
+	runPending()
+
+	app, _ := lookupCallbackData(userData).(*App)
+	if app != nil && app.onUpdate != nil {
 		app.onUpdate()
 	}
 }
 
 //export winResizeCallback
-func winResizeCallback(userData unsafe.Pointer, width, height C.uint) {
-	win := callbackData[userData].(*Window)
-	if win != nil {
-		win.onResize(uint(width), uint(height))
+func winResizeCallback(userData C.uintptr_t, window C.ULWindow, width, height C.uint) {
+	if win, _ := lookupCallbackData(userData).(*Window); win != nil {
+		for _, cb := range win.events.get(eventResize) {
+			cb.(func(width, height uint))(uint(width), uint(height))
+		}
 	}
 }
 
 //export winCloseCallback
-func winCloseCallback(userData unsafe.Pointer) {
-	win := callbackData[userData].(*Window)
-	if win != nil {
-		win.onClose()
+func winCloseCallback(userData C.uintptr_t, window C.ULWindow) {
+	if win, _ := lookupCallbackData(userData).(*Window); win != nil {
+		for _, cb := range win.events.get(eventClose) {
+			cb.(func())()
+		}
 	}
 }
 
 //export viewBeginLoadingCallback
-func viewBeginLoadingCallback(userData unsafe.Pointer, caller C.ULView) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onBeginLoading()
+func viewBeginLoadingCallback(userData C.uintptr_t, caller C.ULView,
+	frameId C.ulonglong, isMainFrame C.bool, url C.ULString) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		ev := LoadEvent{FrameID: uint64(frameId), IsMainFrame: bool(isMainFrame), URL: decodeULString(url)}
+		for _, cb := range view.events.get(eventBeginLoading) {
+			cb.(func(LoadEvent))(ev)
+		}
 	}
 }
 
 //export viewFinishLoadingCallback
-func viewFinishLoadingCallback(userData unsafe.Pointer, caller C.ULView) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onFinishLoading()
+func viewFinishLoadingCallback(userData C.uintptr_t, caller C.ULView,
+	frameId C.ulonglong, isMainFrame C.bool, url C.ULString) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		ev := LoadEvent{FrameID: uint64(frameId), IsMainFrame: bool(isMainFrame), URL: decodeULString(url)}
+		for _, cb := range view.events.get(eventFinishLoading) {
+			cb.(func(LoadEvent))(ev)
+		}
+	}
+}
+
+//export viewFailLoadingCallback
+func viewFailLoadingCallback(userData C.uintptr_t, caller C.ULView,
+	frameId C.ulonglong, isMainFrame C.bool, url C.ULString,
+	description, errorDomain C.ULString, errorCode C.int) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		err := &LoadError{
+			LoadEvent:   LoadEvent{FrameID: uint64(frameId), IsMainFrame: bool(isMainFrame), URL: decodeULString(url)},
+			Description: decodeULString(description),
+			Domain:      decodeULString(errorDomain),
+			Code:        int(errorCode),
+		}
+		for _, cb := range view.events.get(eventFailLoading) {
+			cb.(func(*LoadError))(err)
+		}
 	}
 }
 
 //export viewUpdateHistoryCallback
-func viewUpdateHistoryCallback(userData unsafe.Pointer, caller C.ULView) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onUpdateHistory()
+func viewUpdateHistoryCallback(userData C.uintptr_t, caller C.ULView) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		for _, cb := range view.events.get(eventUpdateHistory) {
+			cb.(func())()
+		}
 	}
 }
 
 //export viewChangeTitleCallback
-func viewChangeTitleCallback(userData unsafe.Pointer, caller C.ULView, title C.ULString) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onChangeTitle(decodeULString(title))
+func viewChangeTitleCallback(userData C.uintptr_t, caller C.ULView, title C.ULString) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		t := decodeULString(title)
+		for _, cb := range view.events.get(eventChangeTitle) {
+			cb.(func(string))(t)
+		}
 	}
 }
 
 //export viewChangeURLCallback
-func viewChangeURLCallback(userData unsafe.Pointer, caller C.ULView, url C.ULString) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onChangeURL(decodeULString(url))
+func viewChangeURLCallback(userData C.uintptr_t, caller C.ULView, url C.ULString) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		u := decodeULString(url)
+		for _, cb := range view.events.get(eventChangeURL) {
+			cb.(func(string))(u)
+		}
 	}
 }
 
 //export viewChangeCursorCallback
-func viewChangeCursorCallback(userData unsafe.Pointer, caller C.ULView, cursor C.ULCursor) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onChangeCursor(Cursor(cursor))
+func viewChangeCursorCallback(userData C.uintptr_t, caller C.ULView, cursor C.ULCursor) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		for _, cb := range view.events.get(eventChangeCursor) {
+			cb.(func(Cursor))(Cursor(cursor))
+		}
 	}
 }
 
 //export viewDOMReadyCallback
-func viewDOMReadyCallback(userData unsafe.Pointer, caller C.ULView) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onDOMReady()
+func viewDOMReadyCallback(userData C.uintptr_t, caller C.ULView,
+	frameId C.ulonglong, isMainFrame C.bool, url C.ULString) {
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		ev := LoadEvent{FrameID: uint64(frameId), IsMainFrame: bool(isMainFrame), URL: decodeULString(url)}
+		for _, cb := range view.events.get(eventDOMReady) {
+			cb.(func(LoadEvent))(ev)
+		}
 	}
 }
 
 //export viewConsoleMessageCallback
-func viewConsoleMessageCallback(userData unsafe.Pointer, caller C.ULView,
+func viewConsoleMessageCallback(userData C.uintptr_t, caller C.ULView,
 	source C.ULMessageSource, level C.ULMessageLevel,
 	message C.ULString, line, col C.uint,
 	sourceId C.ULString) {
-	view := callbackData[userData].(*View)
-	if view != nil {
-		view.onConsoleMessage(
-			MessageSource(source),
-			MessageLevel(level),
-			decodeULString(message),
-			uint(line), uint(col),
-			decodeULString(sourceId))
+	if view, _ := lookupCallbackData(userData).(*View); view != nil {
+		msg := decodeULString(message)
+		id := decodeULString(sourceId)
+		for _, cb := range view.events.get(eventConsoleMessage) {
+			cb.(func(MessageSource, MessageLevel, string, uint, uint, string))(
+				MessageSource(source),
+				MessageLevel(level),
+				msg,
+				uint(line), uint(col),
+				id)
+		}
 	}
 }
 
 //export objFunctionCallback
 func objFunctionCallback(ctx C.JSContextRef, function C.JSObjectRef, this C.JSObjectRef,
-	nargs C.size_t, args *C.JSValueRef, exc *C.JSValueRef) C.JSValueRef {
-
-	if data := callbackData[unsafe.Pointer(function)]; data != nil {
-		if cb, ok := data.(FunctionCallback); ok {
-			f := &JSObject{ctx: ctx, obj: function}
-			fthis := &JSObject{ctx: ctx, obj: this}
-			fargs := make([]*JSValue, nargs)
-
-			if int(nargs) > 0 {
-				var ja []C.JSValueRef
-				sl := (*reflect.SliceHeader)(unsafe.Pointer(&ja))
-				sl.Cap = int(nargs)
-				sl.Len = int(nargs)
-				sl.Data = uintptr(unsafe.Pointer(args))
-
-				for i, v := range ja {
-					fargs[i] = &JSValue{ctx: ctx, val: v}
-				}
+	nargs C.size_t, args *C.JSValueRef, exc *C.JSValueRef) (ret C.JSValueRef) {
+
+	data := lookupCallbackData(C.function_callback_data(function))
+	if data == nil {
+		return C.JSValueMakeNull(ctx)
+	}
+
+	jctx := &JSContext{ctx: ctx}
+
+	defer func() {
+		// don't let a panicking callback take down the process
+		if r := recover(); r != nil {
+			if exc != nil {
+				*exc = jctx.errorValue(fmt.Errorf("panic: %v", r))
 			}
 
-			// FunctionCallback func(function, this *JSObject, args ...*JSValue) *JSValue
-			if ret := cb(f, fthis, fargs...); ret != nil {
-				return ret.val
+			ret = C.JSValueMakeNull(ctx)
+		}
+	}()
+
+	f := &JSObject{ctx: ctx, obj: function}
+	fthis := &JSObject{ctx: ctx, obj: this}
+	fargs := make([]*JSValue, nargs)
+
+	if int(nargs) > 0 {
+		var ja []C.JSValueRef
+		sl := (*reflect.SliceHeader)(unsafe.Pointer(&ja))
+		sl.Cap = int(nargs)
+		sl.Len = int(nargs)
+		sl.Data = uintptr(unsafe.Pointer(args))
+
+		for i, v := range ja {
+			fargs[i] = &JSValue{ctx: ctx, val: v}
+		}
+	}
+
+	switch cb := data.(type) {
+	case FunctionCallback:
+		// FunctionCallback func(function, this *JSObject, args ...*JSValue) *JSValue
+		if ret := cb(f, fthis, fargs...); ret != nil {
+			return ret.val
+		}
+
+	case FunctionCallbackErr:
+		// FunctionCallbackErr func(function, this *JSObject, args ...*JSValue) (*JSValue, error)
+		ret, err := cb(f, fthis, fargs...)
+		if err != nil {
+			if exc != nil {
+				*exc = jctx.errorValue(err)
 			}
-		} else {
-			log.Printf("expected FunctionCallback got %#v\n", data)
+		} else if ret != nil {
+			return ret.val
 		}
+
+	default:
+		log.Printf("expected FunctionCallback got %#v\n", data)
 	}
 
 	return C.JSValueMakeNull(ctx)
 }
 
+//export objFinalizeCallback
+func objFinalizeCallback(object C.JSObjectRef) {
+	deleteCallbackData(C.function_callback_data(object))
+}
+
 type Config struct {
-	cfg C.ULConfig
+	cfg  C.ULConfig
+	view viewOptions
+	bgra bool
+}
+
+// viewOptions are the Config settings that Ultralight applies per View
+// (see Renderer.NewView and Window.NewOverlay).
+type viewOptions struct {
+	enableImages     bool
+	enableJavascript bool
+	deviceScale      float64
+
+	fontFamilyStandard  string
+	fontFamilyFixed     string
+	fontFamilySerif     string
+	fontFamilySansSerif string
+	userAgent           string
 }
 
 type configOption func(c *Config)
@@ -1148,7 +1549,7 @@ func EnableImages(enabled bool) configOption {
 
 func EnableJavascript(enabled bool) configOption {
 	return func(c *Config) {
-		c.EnableImages(enabled)
+		c.EnableJavascript(enabled)
 	}
 }
 
@@ -1200,9 +1601,76 @@ func UserStylesheet(css string) configOption {
 	}
 }
 
+func ResourcePath(path string) configOption {
+	return func(c *Config) {
+		c.ResourcePath(path)
+	}
+}
+
+func CachePath(path string) configOption {
+	return func(c *Config) {
+		c.CachePath(path)
+	}
+}
+
+func ForceRepaint(enabled bool) configOption {
+	return func(c *Config) {
+		c.ForceRepaint(enabled)
+	}
+}
+
+func AnimationTimerDelay(delay float64) configOption {
+	return func(c *Config) {
+		c.AnimationTimerDelay(delay)
+	}
+}
+
+func ScrollTimerDelay(delay float64) configOption {
+	return func(c *Config) {
+		c.ScrollTimerDelay(delay)
+	}
+}
+
+func RecycleDelay(delay float64) configOption {
+	return func(c *Config) {
+		c.RecycleDelay(delay)
+	}
+}
+
+func MemoryCacheSize(size uint) configOption {
+	return func(c *Config) {
+		c.MemoryCacheSize(size)
+	}
+}
+
+func PageCacheSize(size uint) configOption {
+	return func(c *Config) {
+		c.PageCacheSize(size)
+	}
+}
+
+func OverrideRAMSize(size uint) configOption {
+	return func(c *Config) {
+		c.OverrideRAMSize(size)
+	}
+}
+
+func MinLargeHeapSize(size uint) configOption {
+	return func(c *Config) {
+		c.MinLargeHeapSize(size)
+	}
+}
+
+func MinSmallHeapSize(size uint) configOption {
+	return func(c *Config) {
+		c.MinSmallHeapSize(size)
+	}
+}
+
 // Create config with default values (see <Ultralight/platform/Config.h>).
 func NewConfig(options ...configOption) *Config {
-	c := &Config{cfg: C.ulCreateConfig()}
+	c := &Config{cfg: C.ulCreateConfig(),
+		view: viewOptions{enableImages: true, enableJavascript: true, deviceScale: 1.0}}
 
 	for _, opt := range options {
 		opt(c)
@@ -1219,70 +1687,59 @@ func (c *Config) Destroy() {
 
 // Set whether images should be enabled (Default = True)
 func (c *Config) EnableImages(enabled bool) {
-	C.ulConfigSetEnableImages(c.cfg, C.bool(enabled))
+	c.view.enableImages = enabled
 }
 
 // Set whether JavaScript should be eanbled (Default = True)
 func (c *Config) EnableJavascript(enabled bool) {
-	C.ulConfigSetEnableJavaScript(c.cfg, C.bool(enabled))
+	c.view.enableJavascript = enabled
 }
 
 // Set whether we should use BGRA byte order (instead of RGBA) for View
 // bitmaps. (Default = False)
+//
+// Ultralight paints in BGRA, so with the default the pixels are converted
+// to RGBA when copied out of the View bitmaps.
 func (c *Config) UseBGRAForOffscreenRendering(enabled bool) {
-	C.ulConfigSetUseBGRAForOffscreenRendering(c.cfg, C.bool(enabled))
+	c.bgra = enabled
 }
 
 // Set the amount that the application DPI has been scaled, used for
 // scaling device coordinates to pixels and oversampling raster shapes.
 // (Default = 1.0)
 func (c *Config) DeviceScaleHint(value float64) {
-	C.ulConfigSetDeviceScaleHint(c.cfg, C.double(value))
+	c.view.deviceScale = value
 }
 
 // Set default font-family to use (Default = Times New Roman)
 func (c *Config) FontFamilyStandard(fontName string) {
-	s := C.CString(fontName)
-	uls := C.ulCreateString(s)
-
-	defer func() {
-		C.ulDestroyString(uls)
-		C.free(unsafe.Pointer(s))
-	}()
-
-	C.ulConfigSetFontFamilyStandard(c.cfg, uls)
+	c.view.fontFamilyStandard = fontName
 }
 
 // Set default font-family to use for fixed fonts, eg <pre> and <code>.
 // (Default = Courier New)
 func (c *Config) FontFamilyFixed(fontName string) {
-	s := C.CString(fontName)
-	uls := C.ulCreateString(s)
-
-	defer func() {
-		C.ulDestroyString(uls)
-		C.free(unsafe.Pointer(s))
-	}()
-
-	C.ulConfigSetFontFamilyFixed(c.cfg, uls)
+	c.view.fontFamilyFixed = fontName
 }
 
 // Set default font-family to use for serif fonts. (Default = Times New Roman)
 func (c *Config) FontFamilySerif(fontName string) {
-	s := C.CString(fontName)
-	uls := C.ulCreateString(s)
-
-	defer func() {
-		C.ulDestroyString(uls)
-		C.free(unsafe.Pointer(s))
-	}()
-
-	C.ulConfigSetFontFamilySerif(c.cfg, uls)
+	c.view.fontFamilySerif = fontName
 }
 
 // Set default font-family to use for sans-serif fonts. (Default = Arial)
 func (c *Config) FontFamilySansSerif(fontName string) {
-	s := C.CString(fontName)
+	c.view.fontFamilySansSerif = fontName
+}
+
+// Set user agent string. (See <Ultralight/platform/Config.h> for the default)
+func (c *Config) UserAgent(agent string) {
+	c.view.userAgent = agent
+}
+
+// Set user stylesheet (CSS). (Default = Empty)
+func (c *Config) UserStylesheet(css string) {
+	s := C.CString(css)
 	uls := C.ulCreateString(s)
 
 	defer func() {
@@ -1290,12 +1747,14 @@ func (c *Config) FontFamilySansSerif(fontName string) {
 		C.free(unsafe.Pointer(s))
 	}()
 
-	C.ulConfigSetFontFamilySansSerif(c.cfg, uls)
+	C.ulConfigSetUserStylesheet(c.cfg, uls)
 }
 
-// Set user agent string. (See <Ultralight/platform/Config.h> for the default)
-func (c *Config) UserAgent(agent string) {
-	s := C.CString(agent)
+// Set the path prefix of Ultralight's bundled resources (eg, cacert.pem and
+// other localized resources), relative to the file system (see SetFileSystem).
+// (Default = "resources/")
+func (c *Config) ResourcePath(path string) {
+	s := C.CString(path)
 	uls := C.ulCreateString(s)
 
 	defer func() {
@@ -1303,12 +1762,13 @@ func (c *Config) UserAgent(agent string) {
 		C.free(unsafe.Pointer(s))
 	}()
 
-	C.ulConfigSetUserAgent(c.cfg, uls)
+	C.ulConfigSetResourcePathPrefix(c.cfg, uls)
 }
 
-// Set user stylesheet (CSS). (Default = Empty)
-func (c *Config) UserStylesheet(css string) {
-	s := C.CString(css)
+// Set the file path to a writable directory that will be used to store
+// cookies, cached resources, and other persistent data. (Default = Empty)
+func (c *Config) CachePath(path string) {
+	s := C.CString(path)
 	uls := C.ulCreateString(s)
 
 	defer func() {
@@ -1316,38 +1776,143 @@ func (c *Config) UserStylesheet(css string) {
 		C.free(unsafe.Pointer(s))
 	}()
 
-	C.ulConfigSetUserStylesheet(c.cfg, uls)
+	C.ulConfigSetCachePath(c.cfg, uls)
+}
+
+// Set whether or not we should continuously repaint any Views or compositor
+// layers, regardless if they are dirty or not. This is mainly used to
+// diagnose painting/shader issues. (Default = False)
+func (c *Config) ForceRepaint(enabled bool) {
+	C.ulConfigSetForceRepaint(c.cfg, C.bool(enabled))
+}
+
+// Set the amount of time to wait before triggering another repaint when a
+// CSS animation is active, in seconds. (Default = 1.0 / 60.0)
+func (c *Config) AnimationTimerDelay(delay float64) {
+	C.ulConfigSetAnimationTimerDelay(c.cfg, C.double(delay))
+}
+
+// Set the amount of time to wait before triggering another repaint when a
+// smooth scroll animation is active, in seconds. (Default = 1.0 / 90.0)
+func (c *Config) ScrollTimerDelay(delay float64) {
+	C.ulConfigSetScrollTimerDelay(c.cfg, C.double(delay))
+}
+
+// Set the amount of time to wait before running the recycler (will attempt
+// to return excess memory back to the system), in seconds. (Default = 4.0)
+func (c *Config) RecycleDelay(delay float64) {
+	C.ulConfigSetRecycleDelay(c.cfg, C.double(delay))
+}
+
+// Set the size of WebCore's memory cache for decoded images, scripts, and
+// other assets in bytes. (Default = 64 * 1024 * 1024)
+func (c *Config) MemoryCacheSize(size uint) {
+	C.ulConfigSetMemoryCacheSize(c.cfg, C.uint(size))
+}
+
+// Set the number of pages to keep in the cache. (Default = 0)
+func (c *Config) PageCacheSize(size uint) {
+	C.ulConfigSetPageCacheSize(c.cfg, C.uint(size))
+}
+
+// Set the system's physical RAM size in bytes, used by JavaScriptCore to
+// tune its garbage collection heuristics. A value of 0 will use the real
+// system RAM size. (Default = 0)
+func (c *Config) OverrideRAMSize(size uint) {
+	C.ulConfigSetOverrideRAMSize(c.cfg, C.uint(size))
+}
+
+// Set the minimum size of large VM heaps in JavaScriptCore, in bytes.
+// Increasing this value can improve performance at the expense of
+// higher memory usage. (Default = 32 * 1024 * 1024)
+func (c *Config) MinLargeHeapSize(size uint) {
+	C.ulConfigSetMinLargeHeapSize(c.cfg, C.uint(size))
+}
+
+// Set the minimum size of small VM heaps in JavaScriptCore, in bytes.
+// Increasing this value can improve performance at the expense of
+// higher memory usage. (Default = 1 * 1024 * 1024)
+func (c *Config) MinSmallHeapSize(size uint) {
+	C.ulConfigSetMinSmallHeapSize(c.cfg, C.uint(size))
 }
 
 type Renderer struct {
-	rnd C.ULRenderer
+	rnd  C.ULRenderer
+	view viewOptions
+	bgra bool
 }
 
 // Create renderer (create this only once per application lifetime).
+//
+// The AppCore font loader and file system (relative to the current directory)
+// are used, unless set with SetFontLoader and SetFileSystem.
 func NewRenderer(c *Config) *Renderer {
-	return &Renderer{rnd: C.ulCreateRenderer(c.cfg)}
+	if fontLoader() == nil {
+		C.ulEnablePlatformFontLoader()
+	}
+	if fileSystem() == nil {
+		enablePlatformFileSystem(".")
+	}
+
+	r := &Renderer{rnd: C.ulCreateRenderer(c.cfg), view: c.view, bgra: c.bgra}
+	currentRenderer = r
+	return r
+}
+
+// enablePlatformFileSystem sets the AppCore file system, rooted at baseDir.
+func enablePlatformFileSystem(baseDir string) {
+	uls := createULString(baseDir)
+	defer C.ulDestroyString(uls)
+
+	C.ulEnablePlatformFileSystem(uls)
+}
+
+// createULString returns a new ULString, to be destroyed with ulDestroyString
+// unless it is returned to the library (that takes ownership).
+func createULString(s string) C.ULString {
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+
+	return C.ulCreateString(cs)
 }
 
 // Destroy renderer.
 func (r *Renderer) Destroy() {
+	if currentRenderer == r {
+		currentRenderer = nil
+	}
+
 	C.ulDestroyRenderer(r.rnd)
 	r.rnd = nil
 }
 
-// Update timers and dispatch internal callbacks (JavaScript and network)
+// Update timers and dispatch internal callbacks (JavaScript and network).
+// This also runs the functions queued with Dispatch, and should be called
+// from the main goroutine.
 func (r *Renderer) Update() {
+	runPending()
 	C.ulUpdate(r.rnd)
 }
 
-// Render all active Views to their respective bitmaps.
+// Render all active Views to their respective surfaces.
 func (r *Renderer) Render() {
+	C.ulRefreshDisplay(r.rnd, 0)
 	C.ulRender(r.rnd)
+	notifySurfacesPainted()
+	notifyViewsPainted()
 }
 
 // Destroy an overlay.
 func (ovl *Overlay) Destroy() {
+	if ovl.ovl == nil {
+		return
+	}
+
+	ovl.view.clearCallbacks()
 	C.ulDestroyOverlay(ovl.ovl)
+	C.ulDestroyView(ovl.view.view)
 	ovl.ovl = nil
+	ovl.view.view = nil
 }
 
 //
@@ -1392,30 +1957,125 @@ func (ovl *Overlay) Resize(width, height uint) {
 }
 
 // Create a View with certain size (in device coordinates).
+//
+// The View uses the CPU renderer and the view settings of the Renderer Config
+// (images, JavaScript, device scale, fonts and user agent).
 func (r *Renderer) NewView(width, height uint, transparent bool) *View {
-	return &View{view: C.ulCreateView(r.rnd, C.uint(width), C.uint(height), C.bool(transparent))}
+	vc := r.view.viewConfig(false, transparent)
+	defer C.ulDestroyViewConfig(vc)
+
+	view := C.ulCreateView(r.rnd, C.uint(width), C.uint(height), vc, nil)
+	bindSurface(view)
+	return &View{view: view, bgra: r.bgra}
+}
+
+// viewConfig creates the ULViewConfig for a new View (to be destroyed with ulDestroyViewConfig).
+func (o *viewOptions) viewConfig(accelerated, transparent bool) C.ULViewConfig {
+	vc := C.ulCreateViewConfig()
+	C.ulViewConfigSetIsAccelerated(vc, C.bool(accelerated))
+	C.ulViewConfigSetIsTransparent(vc, C.bool(transparent))
+	C.ulViewConfigSetInitialDeviceScale(vc, C.double(o.deviceScale))
+	C.ulViewConfigSetEnableImages(vc, C.bool(o.enableImages))
+	C.ulViewConfigSetEnableJavaScript(vc, C.bool(o.enableJavascript))
+
+	setString := func(set func(C.ULViewConfig, C.ULString), value string) {
+		if value != "" {
+			uls := createULString(value)
+			set(vc, uls)
+			C.ulDestroyString(uls)
+		}
+	}
+
+	setString(func(vc C.ULViewConfig, s C.ULString) { C.ulViewConfigSetFontFamilyStandard(vc, s) }, o.fontFamilyStandard)
+	setString(func(vc C.ULViewConfig, s C.ULString) { C.ulViewConfigSetFontFamilyFixed(vc, s) }, o.fontFamilyFixed)
+	setString(func(vc C.ULViewConfig, s C.ULString) { C.ulViewConfigSetFontFamilySerif(vc, s) }, o.fontFamilySerif)
+	setString(func(vc C.ULViewConfig, s C.ULString) { C.ulViewConfigSetFontFamilySansSerif(vc, s) }, o.fontFamilySansSerif)
+	setString(func(vc C.ULViewConfig, s C.ULString) { C.ulViewConfigSetUserAgent(vc, s) }, o.userAgent)
+	return vc
 }
 
 // Destroy a View.
 func (v *View) Destroy() {
-	v.OnBeginLoading(nil)
-	v.OnFinishLoading(nil)
-	v.OnUpdateHistory(nil)
-	v.OnDOMReady(nil)
-	v.OnConsoleMessage(nil)
+	v.clearCallbacks()
 	C.ulDestroyView(v.view)
 	v.view = nil
 }
 
+func (v *View) callbackData() C.uintptr_t {
+	if v.cbData == 0 {
+		v.cbData = newCallbackData(v)
+	}
+
+	return v.cbData
+}
+
+// clearCallbacks removes all the View callbacks and releases the callback data.
+func (v *View) clearCallbacks() {
+	v.events.clear()
+	for kind := eventBeginLoading; kind <= eventPaint; kind++ {
+		v.updateCallback(kind)
+	}
+	deleteCallbackData(v.cbData)
+	v.cbData = 0
+}
+
+// Check if bitmap is dirty (has changed since last call to Bitmap).
+func (v *View) IsBitmapDirty() bool {
+	surface := C.ulViewGetSurface(v.view)
+	if surface == nil {
+		return false
+	}
+
+	r := C.ulSurfaceGetDirtyBounds(surface)
+	return r.right > r.left && r.bottom > r.top
+}
+
 // Get bitmap (will reset the dirty flag).
-//func (v *View) Bitmap() image.Image {
-//    bitmap := C.ulViewGetBitmap(v.view)
-//}
+// It returns nil if the View doesn't paint into a bitmap.
+func (v *View) Bitmap() *Bitmap {
+	bmp := v.bitmap()
+	if bmp == nil {
+		return nil
+	}
+
+	C.ulSurfaceClearDirtyBounds(C.ulViewGetSurface(v.view))
+	return &Bitmap{bmp: bmp, bgra: v.bgra}
+}
+
+// bitmap returns the bitmap of the View surface (nil if there isn't one,
+// or the View paints into a Surface, see SetSurfaceFactory).
+func (v *View) bitmap() C.ULBitmap {
+	if v.view == nil {
+		return nil
+	}
+
+	surface := C.ulViewGetSurface(v.view)
+	if surface == nil || surfaceFor(v.view) != nil {
+		return nil
+	}
+
+	return C.ulBitmapSurfaceGetBitmap(surface)
+}
+
+// Get a copy of the bitmap as an image (will reset the dirty flag).
+// It returns nil if the View doesn't paint into a bitmap.
+func (v *View) Image() image.Image {
+	if b := v.Bitmap(); b != nil {
+		return b.Image()
+	}
+
+	return nil
+}
 
 // Write bitmap to a PNG on disk.
 func (v *View) WriteToPNG(filename string) bool {
+	bmp := v.bitmap()
+	if bmp == nil {
+		return false
+	}
+
 	path := C.CString(filename)
 	defer C.free(unsafe.Pointer(path))
 
-	return bool(C.ulBitmapWritePNG(C.ulViewGetBitmap(v.view), path))
+	return bool(C.ulBitmapWritePNG(bmp, path))
 }
@@ -0,0 +1,112 @@
+package ultralight
+
+import (
+	"context"
+	"time"
+)
+
+// WaitCondition specifies when LoadURLAndWait and LoadHTMLAndWait return.
+type WaitCondition int
+
+const (
+	// Wait until the main frame finishes loading (the default).
+	WaitLoad WaitCondition = iota
+
+	// Wait until the main frame document is ready (see OnDOMReady).
+	WaitDOMReady
+
+	// Wait until the main frame finishes loading and no other frame
+	// has been loading for NetworkIdleTime.
+	WaitNetworkIdle
+)
+
+// NetworkIdleTime is how long all frames should be idle for WaitNetworkIdle.
+var NetworkIdleTime = 500 * time.Millisecond
+
+// LoadURLAndWait loads a URL into main frame and waits for the wait condition
+// (WaitLoad if not specified), running the Renderer event loop.
+//
+// It returns a *LoadError if the main frame fails to load, or the context
+// error if ctx is done before the page is loaded (in this case the load is stopped).
+func (view *View) LoadURLAndWait(ctx context.Context, url string, wait ...WaitCondition) error {
+	return view.loadAndWait(ctx, func() { view.LoadURL(url) }, wait)
+}
+
+// LoadHTMLAndWait loads a raw string of html and waits for the wait condition
+// (see LoadURLAndWait).
+func (view *View) LoadHTMLAndWait(ctx context.Context, html string, wait ...WaitCondition) error {
+	return view.loadAndWait(ctx, func() { view.LoadHTML(html) }, wait)
+}
+
+func (view *View) loadAndWait(ctx context.Context, load func(), wait []WaitCondition) error {
+	cond := WaitLoad
+	if len(wait) > 0 {
+		cond = wait[0]
+	}
+
+	var loadErr error
+	var idleSince time.Time
+
+	loaded, domReady := false, false
+	pending := map[uint64]bool{}
+
+	subs := []*Subscription{
+		view.AddBeginLoadingListener(func(ev LoadEvent) {
+			pending[ev.FrameID] = true
+		}),
+		view.AddFinishLoadingListener(func(ev LoadEvent) {
+			delete(pending, ev.FrameID)
+			if ev.IsMainFrame {
+				loaded = true
+			}
+			if len(pending) == 0 {
+				idleSince = time.Now()
+			}
+		}),
+		view.AddFailLoadingListener(func(err *LoadError) {
+			delete(pending, err.FrameID)
+			if err.IsMainFrame {
+				loadErr = err
+			}
+			if len(pending) == 0 {
+				idleSince = time.Now()
+			}
+		}),
+		view.AddDOMReadyListener(func(ev LoadEvent) {
+			if ev.IsMainFrame {
+				domReady = true
+			}
+		}),
+	}
+
+	defer func() {
+		for _, sub := range subs {
+			sub.Cancel()
+		}
+	}()
+
+	load()
+
+	err := pumpUntil(ctx, func() bool {
+		if loadErr != nil {
+			return true
+		}
+
+		switch cond {
+		case WaitDOMReady:
+			return domReady
+
+		case WaitNetworkIdle:
+			return loaded && len(pending) == 0 && time.Since(idleSince) >= NetworkIdleTime
+		}
+
+		return loaded
+	})
+
+	if err != nil {
+		view.Stop()
+		return err
+	}
+
+	return loadErr
+}